@@ -0,0 +1,43 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/hyperledger/firefly/internal/batchpool"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/oapispec"
+)
+
+var routeGetStatusBatchPool = &oapispec.Route{
+	Name:            "getStatusBatchPool",
+	Path:            "status/batchpool",
+	Method:          http.MethodGet,
+	PathParams:      nil,
+	QueryParams:     nil,
+	Description:     i18n.MsgTBD,
+	JSONInputValue:  func() interface{} { return nil },
+	JSONOutputValue: func() interface{} { return &batchpool.PoolStatus{} },
+	JSONOutputCodes: []int{http.StatusOK},
+	Extensions: &coreExtensions{
+		CoreJSONHandler: func(r *oapispec.APIRequest, cr *coreRequest) (output interface{}, err error) {
+			status := cr.or.BatchPool().Status()
+			return &status, nil
+		},
+	},
+}
@@ -0,0 +1,47 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly/internal/batchpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetStatusBatchPool(t *testing.T) {
+	o, r := newTestAPIServer()
+	o.On("Authorize", mock.Anything, mock.Anything).Return(nil)
+
+	pool := batchpool.New(0)
+	pool.Add(&batchpool.Item{Identity: "alice", Nonce: 0, SubmittedAt: time.Now()})
+	o.On("BatchPool").Return(pool)
+
+	req := httptest.NewRequest("GET", "/api/v1/status/batchpool", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Result().StatusCode)
+
+	var status batchpool.PoolStatus
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.Equal(t, 1, status.Ready)
+}
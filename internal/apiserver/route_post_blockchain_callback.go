@@ -0,0 +1,51 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/oapispec"
+)
+
+// routePostBlockchainCallback accepts the raw, protocol-specific callback body a blockchain plugin's remote
+// agent posts back out-of-band (e.g. ARC's X-CallbackUrl), and forwards it to the active plugin's
+// blockchain.CallbackHandler, if it implements one
+var routePostBlockchainCallback = &oapispec.Route{
+	Name:            "postBlockchainCallback",
+	Path:            "namespaces/{ns}/blockchain/callback",
+	Method:          http.MethodPost,
+	PathParams:      []*oapispec.PathParam{{Name: "ns", Description: i18n.MsgTBD}},
+	QueryParams:     nil,
+	Description:     i18n.MsgTBD,
+	JSONInputValue:  func() interface{} { return &json.RawMessage{} },
+	JSONOutputValue: func() interface{} { return nil },
+	JSONOutputCodes: []int{http.StatusNoContent},
+	Extensions: &coreExtensions{
+		CoreJSONHandler: func(r *oapispec.APIRequest, cr *coreRequest) (output interface{}, err error) {
+			raw := r.Input.(*json.RawMessage)
+			handler, ok := cr.or.Blockchain().(blockchain.CallbackHandler)
+			if !ok {
+				return nil, i18n.NewError(cr.ctx, i18n.MsgBlockchainUnsupported, "HandleCallback", "the configured blockchain plugin")
+			}
+			return nil, handler.HandleCallback(cr.ctx, *raw)
+		},
+	},
+}
@@ -0,0 +1,74 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/firefly/mocks/blockchainmocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostBlockchainCallbackUnsupported(t *testing.T) {
+	o, r := newTestAPIServer()
+	o.On("Authorize", mock.Anything, mock.Anything).Return(nil)
+	bi := &blockchainmocks.Plugin{}
+	o.On("Blockchain").Return(bi)
+
+	req := httptest.NewRequest("POST", "/api/v1/namespaces/ns1/blockchain/callback", bytes.NewReader([]byte(`{"txid":"abc"}`)))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	res := httptest.NewRecorder()
+
+	r.ServeHTTP(res, req)
+
+	// the generated blockchainmocks.Plugin doesn't implement blockchain.CallbackHandler, so this must fail
+	// cleanly rather than panic on a bad type assertion
+	assert.Equal(t, 500, res.Result().StatusCode)
+}
+
+// callbackCapablePlugin composes blockchainmocks.Plugin (satisfying blockchain.Plugin) with a real
+// HandleCallback, since CallbackHandler is an optional interface the generated mock doesn't implement
+type callbackCapablePlugin struct {
+	*blockchainmocks.Plugin
+	handled chan []byte
+}
+
+func (p *callbackCapablePlugin) HandleCallback(ctx context.Context, body []byte) error {
+	p.handled <- body
+	return nil
+}
+
+func TestPostBlockchainCallbackForwarded(t *testing.T) {
+	o, r := newTestAPIServer()
+	o.On("Authorize", mock.Anything, mock.Anything).Return(nil)
+	bi := &callbackCapablePlugin{Plugin: &blockchainmocks.Plugin{}, handled: make(chan []byte, 1)}
+	o.On("Blockchain").Return(bi)
+
+	body := []byte(`{"txid":"abc","txStatus":"MINED"}`)
+	req := httptest.NewRequest("POST", "/api/v1/namespaces/ns1/blockchain/callback", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	res := httptest.NewRecorder()
+
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, 204, res.Result().StatusCode)
+	assert.JSONEq(t, string(body), string(<-bi.handled))
+}
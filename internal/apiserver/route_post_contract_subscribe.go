@@ -0,0 +1,58 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/oapispec"
+)
+
+// contractSubscriptionInput is the body accepted by routePostContractSubscribe: an ABI event fragment plus
+// the addressing/filter info needed to turn it into a blockchain.EventSubscription
+type contractSubscriptionInput struct {
+	Location  map[string]interface{} `json:"location"`
+	Event     blockchain.ABIEvent    `json:"event"`
+	FromBlock string                 `json:"fromBlock,omitempty"`
+	Filter    map[string]interface{} `json:"filter,omitempty"`
+}
+
+var routePostContractSubscribe = &oapispec.Route{
+	Name:            "postContractSubscribe",
+	Path:            "namespaces/{ns}/contracts/subscriptions",
+	Method:          http.MethodPost,
+	PathParams:      []*oapispec.PathParam{{Name: "ns", Description: i18n.MsgTBD}},
+	QueryParams:     nil,
+	Description:     i18n.MsgTBD,
+	JSONInputValue:  func() interface{} { return &contractSubscriptionInput{} },
+	JSONOutputValue: func() interface{} { return blockchain.SubscriptionID("") },
+	JSONOutputCodes: []int{http.StatusOK},
+	Extensions: &coreExtensions{
+		CoreJSONHandler: func(r *oapispec.APIRequest, cr *coreRequest) (output interface{}, err error) {
+			input := r.Input.(*contractSubscriptionInput)
+			subID, err := cr.or.Blockchain().SubscribeEvents(cr.ctx, blockchain.EventSubscription{
+				Location:  input.Location,
+				Event:     input.Event,
+				FromBlock: input.FromBlock,
+				Filter:    input.Filter,
+			})
+			return subID, err
+		},
+	},
+}
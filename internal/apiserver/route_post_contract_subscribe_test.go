@@ -0,0 +1,57 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/hyperledger/firefly/mocks/blockchainmocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostContractSubscribe(t *testing.T) {
+	o, r := newTestAPIServer()
+	o.On("Authorize", mock.Anything, mock.Anything).Return(nil)
+	bi := &blockchainmocks.Plugin{}
+	o.On("Blockchain").Return(bi)
+
+	input := &contractSubscriptionInput{
+		Event: blockchain.ABIEvent{
+			Name: "Transfer",
+			Inputs: []blockchain.ABIParameter{
+				{Name: "from", Type: "address", Indexed: true},
+				{Name: "to", Type: "address", Indexed: true},
+				{Name: "value", Type: "uint256"},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(input)
+	req := httptest.NewRequest("POST", "/api/v1/namespaces/ns1/contracts/subscriptions", &buf)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	res := httptest.NewRecorder()
+
+	bi.On("SubscribeEvents", mock.Anything, mock.AnythingOfType("blockchain.EventSubscription")).Return(blockchain.SubscriptionID("sub1"), nil)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Result().StatusCode)
+}
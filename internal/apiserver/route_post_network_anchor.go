@@ -0,0 +1,64 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/interop"
+	"github.com/hyperledger/firefly/internal/oapispec"
+	"github.com/hyperledger/firefly/pkg/core"
+)
+
+var routePostNetworkAnchor = &oapispec.Route{
+	Name:            "postNetworkAnchor",
+	Path:            "network/anchor",
+	Method:          http.MethodPost,
+	PathParams:      nil,
+	QueryParams:     nil,
+	Description:     i18n.MsgTBD,
+	JSONInputValue:  func() interface{} { return &core.NetworkAnchor{} },
+	JSONOutputValue: func() interface{} { return nil },
+	JSONOutputCodes: []int{http.StatusAccepted},
+	Extensions: &coreExtensions{
+		CoreJSONHandler: func(r *oapispec.APIRequest, cr *coreRequest) (output interface{}, err error) {
+			input := r.Input.(*core.NetworkAnchor)
+			batch, proof := networkAnchorToPlugin(input)
+			return nil, cr.or.Interop().PinAll(cr.ctx, input.SourceChain, batch, proof)
+		},
+	},
+}
+
+// networkAnchorToPlugin converts the pkg/core-local Batch/Proof shapes on a NetworkAnchor request into the
+// internal/blockchain and internal/interop types the plugins themselves operate on
+func networkAnchorToPlugin(input *core.NetworkAnchor) (blockchain.BroadcastBatch, interop.AnchorProof) {
+	path := make([]blockchain.Bytes32, len(input.Proof.Path))
+	for i, p := range input.Proof.Path {
+		path[i] = blockchain.Bytes32(p)
+	}
+	return blockchain.BroadcastBatch{
+			Timestamp:      input.Batch.Timestamp,
+			BatchPaylodRef: blockchain.HexUUID(input.Batch.BatchPayloadRef),
+			BatchID:        blockchain.Bytes32(input.Batch.BatchID),
+		}, interop.AnchorProof{
+			Root:      blockchain.Bytes32(input.Proof.Root),
+			Path:      path,
+			LeafIndex: input.Proof.LeafIndex,
+		}
+}
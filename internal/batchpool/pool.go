@@ -0,0 +1,322 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package batchpool is an in-memory priority queue for outbound batches, sitting between the batch manager
+// and the blockchain plugin. It orders ready-to-submit batches by (explicit priority hint, age, fee estimate),
+// while enforcing a per-identity in-flight cap and respecting nonce dependency ordering - modelled on the
+// transaction pool found in Substrate-based chains.
+package batchpool
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"context"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+)
+
+// WatchState is a point in an Item's submission lifecycle that a Watcher can be notified of
+type WatchState string
+
+const (
+	// WatchReady means the item has no outstanding nonce dependency and is sitting in the ready heap,
+	// waiting to be popped for submission
+	WatchReady WatchState = "ready"
+	// WatchBroadcast means the item has been popped from the pool for submission to the blockchain plugin
+	WatchBroadcast WatchState = "broadcast"
+	// WatchInBlock means the submitted transaction has been observed included in a block, but is not yet
+	// considered final
+	WatchInBlock WatchState = "in_block"
+	// WatchFinalized means MarkFinalized has been called for this item (Confirm itself does not notify watchers)
+	WatchFinalized WatchState = "finalized"
+	// WatchDropped means Fail was called with a retryable error, and the item has been re-queued
+	WatchDropped WatchState = "dropped"
+	// WatchInvalid means Fail was called with a permanent error, and the item will not be retried
+	WatchInvalid WatchState = "invalid"
+)
+
+// Item is a single outbound batch waiting to be submitted, keyed by (Identity, Nonce)
+type Item struct {
+	Identity string
+	Nonce    uint64
+	Batch    blockchain.BroadcastBatch
+
+	// Priority is an explicit hint from the caller (higher submits first), compared before age or fee
+	Priority int
+
+	// GasEstimate is the fee/gas cost reported by blockchain.Plugin.EstimateGas, used as the final tiebreaker
+	GasEstimate uint64
+
+	// SubmittedAt is when the item was added to the pool, used to break ties between equal-priority items
+	// by age (older first). It is not reset when Fail re-queues the item.
+	SubmittedAt time.Time
+
+	retries int
+	index   int // maintained by container/heap
+
+	watchMux sync.Mutex
+	watchers []chan WatchState
+}
+
+// Watch returns a channel that receives every WatchState transition this item goes through from this point
+// on (Ready/Broadcast/InBlock/Finalized/Dropped/Invalid), letting a caller that submitted a batch track its
+// progress without polling the pool. The channel is closed once the item reaches a terminal state
+// (Finalized or Invalid).
+func (item *Item) Watch() <-chan WatchState {
+	ch := make(chan WatchState, 8)
+	item.watchMux.Lock()
+	item.watchers = append(item.watchers, ch)
+	item.watchMux.Unlock()
+	return ch
+}
+
+// notify delivers state to every channel registered via Watch, closing them all first if state is terminal
+func (item *Item) notify(state WatchState) {
+	item.watchMux.Lock()
+	defer item.watchMux.Unlock()
+	for _, ch := range item.watchers {
+		ch <- state
+	}
+	if state == WatchFinalized || state == WatchInvalid {
+		for _, ch := range item.watchers {
+			close(ch)
+		}
+		item.watchers = nil
+	}
+}
+
+// readyHeap orders Items by (Priority desc, SubmittedAt asc - i.e. oldest first, GasEstimate asc)
+type readyHeap []*Item
+
+func (h readyHeap) Len() int { return len(h) }
+
+func (h readyHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	if !h[i].SubmittedAt.Equal(h[j].SubmittedAt) {
+		return h[i].SubmittedAt.Before(h[j].SubmittedAt)
+	}
+	return h[i].GasEstimate < h[j].GasEstimate
+}
+
+func (h readyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *readyHeap) Push(x interface{}) {
+	item := x.(*Item)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *readyHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// Pool is a priority-ordered, per-identity fair mempool of outbound batches
+type Pool struct {
+	mux sync.Mutex
+
+	maxInFlightPerIdentity int
+
+	ready         readyHeap
+	waiting       map[string]map[uint64]*Item // identity -> nonce -> item still blocked on an earlier nonce
+	lastOK        map[string]uint64           // identity -> highest confirmed nonce
+	inFlightCount map[string]int
+}
+
+// PoolStatus is a point-in-time snapshot of a Pool's size, returned by Status and served by the
+// GET /api/v1/status/batchpool route
+type PoolStatus struct {
+	// Ready is the number of items currently in the ready heap, waiting to be popped for submission
+	Ready int `json:"ready"`
+	// Waiting is the number of items held back on an outstanding nonce dependency
+	Waiting int `json:"waiting"`
+	// InFlight is the number of items currently popped for submission but not yet confirmed or failed,
+	// keyed by identity
+	InFlight map[string]int `json:"inFlight"`
+}
+
+// New creates an empty Pool. maxInFlightPerIdentity caps how many of a single identity's batches may be
+// popped out for submission before one of them is confirmed or fails.
+func New(maxInFlightPerIdentity int) *Pool {
+	return &Pool{
+		maxInFlightPerIdentity: maxInFlightPerIdentity,
+		waiting:                make(map[string]map[uint64]*Item),
+		lastOK:                 make(map[string]uint64),
+		inFlightCount:          make(map[string]int),
+	}
+}
+
+// Add enqueues item. If item.Nonce is more than one ahead of the identity's last confirmed nonce, it is held
+// back in the waiting set until Confirm catches the dependency chain up to it.
+func (p *Pool) Add(item *Item) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	item.index = -1
+	if p.isReady(item) {
+		heap.Push(&p.ready, item)
+		item.notify(WatchReady)
+		return
+	}
+	byNonce, ok := p.waiting[item.Identity]
+	if !ok {
+		byNonce = make(map[uint64]*Item)
+		p.waiting[item.Identity] = byNonce
+	}
+	byNonce[item.Nonce] = item
+}
+
+// isReady returns true if item has no outstanding dependency, i.e. it is either nonce 0 for its identity, or
+// the immediately preceding nonce has already been confirmed
+func (p *Pool) isReady(item *Item) bool {
+	if item.Nonce == 0 {
+		return true
+	}
+	last, confirmed := p.lastOK[item.Identity]
+	return confirmed && last == item.Nonce-1
+}
+
+// Pop removes and returns the highest priority ready item whose identity has not hit maxInFlightPerIdentity,
+// or returns ok=false if no such item currently exists (the caller should back off and retry later)
+func (p *Pool) Pop(ctx context.Context) (item *Item, ok bool) {
+	p.mux.Lock()
+	var skipped []*Item
+	for p.ready.Len() > 0 {
+		candidate := heap.Pop(&p.ready).(*Item)
+		if p.maxInFlightPerIdentity > 0 && p.inFlightCount[candidate.Identity] >= p.maxInFlightPerIdentity {
+			skipped = append(skipped, candidate)
+			continue
+		}
+		p.inFlightCount[candidate.Identity]++
+		item, ok = candidate, true
+		break
+	}
+	for _, s := range skipped {
+		heap.Push(&p.ready, s)
+	}
+	p.mux.Unlock()
+
+	if ok {
+		item.notify(WatchBroadcast)
+	}
+	return item, ok
+}
+
+// Confirm marks (identity, nonce) as confirmed, releases its in-flight slot, and promotes any waiting item
+// for the next nonce in the dependency chain into the ready heap
+func (p *Pool) Confirm(identity string, nonce uint64) {
+	p.mux.Lock()
+	p.releaseInFlight(identity)
+	p.lastOK[identity] = nonce
+
+	var promoted *Item
+	byNonce := p.waiting[identity]
+	if byNonce != nil {
+		if next, found := byNonce[nonce+1]; found && p.isReady(next) {
+			delete(byNonce, nonce+1)
+			heap.Push(&p.ready, next)
+			promoted = next
+		}
+	}
+	p.mux.Unlock()
+
+	if promoted != nil {
+		promoted.notify(WatchReady)
+	}
+}
+
+// MarkInBlock notifies any Watcher of item that the submitted transaction has been observed included in a
+// block, without otherwise changing the item's place in the pool (the caller still calls Confirm or Fail
+// once the transaction reaches a final outcome)
+func (item *Item) MarkInBlock() {
+	item.notify(WatchInBlock)
+}
+
+// MarkFinalized notifies any Watcher of item that it has reached its terminal confirmed state, and closes
+// their channels. Pool.Confirm advances the nonce dependency chain but does not notify watchers, so a caller
+// that confirms an item also calls MarkFinalized to close out that item's watchers.
+func (item *Item) MarkFinalized() {
+	item.notify(WatchFinalized)
+}
+
+// Fail releases item's in-flight slot and classifies err via blockchain.IsRetryable/IsInvalid: a retryable
+// failure (nonce gap, mempool conflict) is re-queued into the ready heap with its priority preserved, so it
+// is retried ahead of newer same-priority work; a permanent failure is dropped from the pool entirely and
+// never retried. Any other error is treated as retryable, since the conservative default is to keep trying
+// rather than silently drop a submission.
+func (p *Pool) Fail(item *Item, err error) {
+	p.mux.Lock()
+	p.releaseInFlight(item.Identity)
+
+	permanent := blockchain.IsInvalid(err) && !blockchain.IsRetryable(err)
+	if !permanent {
+		item.retries++
+		heap.Push(&p.ready, item)
+	}
+	p.mux.Unlock()
+
+	if permanent {
+		item.notify(WatchInvalid)
+	} else {
+		item.notify(WatchDropped)
+	}
+}
+
+func (p *Pool) releaseInFlight(identity string) {
+	if p.inFlightCount[identity] > 0 {
+		p.inFlightCount[identity]--
+	}
+}
+
+// Retries returns how many times item has been returned to the pool via Fail
+func (item *Item) Retries() int { return item.retries }
+
+// Status returns a point-in-time snapshot of the pool's size, for reporting via the
+// GET /api/v1/status/batchpool route
+func (p *Pool) Status() PoolStatus {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	waiting := 0
+	for _, byNonce := range p.waiting {
+		waiting += len(byNonce)
+	}
+	inFlight := make(map[string]int, len(p.inFlightCount))
+	for identity, count := range p.inFlightCount {
+		if count > 0 {
+			inFlight[identity] = count
+		}
+	}
+
+	return PoolStatus{
+		Ready:    p.ready.Len(),
+		Waiting:  waiting,
+		InFlight: inFlight,
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batchpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityOrdering(t *testing.T) {
+	p := New(0)
+	now := time.Now()
+	p.Add(&Item{Identity: "alice", Nonce: 0, Priority: 1, SubmittedAt: now})
+	p.Add(&Item{Identity: "bob", Nonce: 0, Priority: 5, SubmittedAt: now})
+
+	item, ok := p.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, "bob", item.Identity)
+
+	item, ok = p.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, "alice", item.Identity)
+
+	_, ok = p.Pop(context.Background())
+	assert.False(t, ok)
+}
+
+func TestPerIdentityInFlightCap(t *testing.T) {
+	p := New(1)
+	now := time.Now()
+	p.Add(&Item{Identity: "alice", Nonce: 0, SubmittedAt: now})
+	p.Add(&Item{Identity: "alice", Nonce: 1, SubmittedAt: now.Add(time.Second)})
+
+	// nonce 1 is blocked behind nonce 0 regardless of in-flight cap
+	item, ok := p.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), item.Nonce)
+
+	_, ok = p.Pop(context.Background())
+	assert.False(t, ok)
+
+	p.Confirm("alice", 0)
+
+	item, ok = p.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), item.Nonce)
+}
+
+func TestFailRequeuesRetryableError(t *testing.T) {
+	p := New(0)
+	item := &Item{Identity: "alice", Nonce: 0, SubmittedAt: time.Now()}
+	p.Add(item)
+
+	popped, ok := p.Pop(context.Background())
+	assert.True(t, ok)
+
+	p.Fail(popped, blockchain.ErrMempoolConflict)
+	assert.Equal(t, 1, popped.Retries())
+
+	popped, ok = p.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, item, popped)
+}
+
+func TestFailDropsPermanentError(t *testing.T) {
+	p := New(0)
+	item := &Item{Identity: "alice", Nonce: 0, SubmittedAt: time.Now()}
+	p.Add(item)
+
+	popped, ok := p.Pop(context.Background())
+	assert.True(t, ok)
+
+	p.Fail(popped, blockchain.ErrMalformedTransaction)
+	assert.Equal(t, 0, popped.Retries())
+
+	_, ok = p.Pop(context.Background())
+	assert.False(t, ok)
+}
+
+func TestWatchReceivesLifecycleTransitions(t *testing.T) {
+	p := New(0)
+	item := &Item{Identity: "alice", Nonce: 0, SubmittedAt: time.Now()}
+	watch := item.Watch()
+
+	p.Add(item)
+	assert.Equal(t, WatchReady, <-watch)
+
+	popped, ok := p.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, WatchBroadcast, <-watch)
+
+	popped.MarkInBlock()
+	assert.Equal(t, WatchInBlock, <-watch)
+
+	p.Confirm("alice", 0)
+	popped.MarkFinalized()
+	assert.Equal(t, WatchFinalized, <-watch)
+
+	_, ok = <-watch
+	assert.False(t, ok, "watch channel should be closed once finalized")
+}
+
+func TestWatchClosedOnPermanentFailure(t *testing.T) {
+	p := New(0)
+	item := &Item{Identity: "alice", Nonce: 0, SubmittedAt: time.Now()}
+	p.Add(item)
+	popped, ok := p.Pop(context.Background())
+	assert.True(t, ok)
+
+	watch := popped.Watch()
+	p.Fail(popped, blockchain.ErrPolicyViolation)
+	assert.Equal(t, WatchInvalid, <-watch)
+
+	_, ok = <-watch
+	assert.False(t, ok, "watch channel should be closed once invalid")
+}
@@ -0,0 +1,348 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package arc implements the blockchain.Plugin interface against an ARC
+// (https://github.com/bitcoin-sv/arc) compatible transaction processor, as used
+// by a number of UTXO-chain miner/agent implementations to accept raw transaction
+// submissions and report back on their propagation/mining status.
+package arc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/log"
+)
+
+const (
+	// ConfigURL is the base URL of the ARC endpoint, e.g. https://arc.example.com
+	ConfigURL = "url"
+
+	defaultMaxRetries = 3
+	defaultRetryBase  = 250 * time.Millisecond
+)
+
+// ARC is a blockchain.Plugin implementation that talks to an ARC-compatible transaction processor
+type ARC struct {
+	ctx        context.Context
+	url        string
+	client     *http.Client
+	events     blockchain.Events
+	maxRetries int
+	retryBase  time.Duration
+}
+
+// New creates a new, uninitialized ARC plugin. Init must be called before use.
+func New() *ARC {
+	return &ARC{
+		client:     &http.Client{},
+		maxRetries: defaultMaxRetries,
+		retryBase:  defaultRetryBase,
+	}
+}
+
+func (a *ARC) ConfigInterface() interface{} {
+	return &Config{}
+}
+
+// Config is the structure into which ARC plugin configuration is marshaled
+type Config struct {
+	URL string `json:"url"`
+}
+
+func (a *ARC) Init(ctx context.Context, conf interface{}, events blockchain.Events) (*blockchain.Capabilities, error) {
+	a.ctx = ctx
+	a.events = events
+
+	c, ok := conf.(*Config)
+	if !ok || c.URL == "" {
+		return nil, i18n.NewError(ctx, i18n.MsgMissingPluginConfig, ConfigURL, "blockchain.arc")
+	}
+	a.url = c.URL
+
+	return &blockchain.Capabilities{
+		GlobalSequencer: true,
+	}, nil
+}
+
+// txSubmission is the ARC /v1/tx request body
+type txSubmission struct {
+	RawTx string `json:"rawTx"`
+}
+
+// txResponse is the ARC /v1/tx and /v1/tx/{id} response body, and the body ARC posts back to
+// SubmitOptions.CallbackURL (ARC re-uses the same representation for all three)
+type txResponse struct {
+	TxID        string `json:"txid"`
+	TxStatus    string `json:"txStatus"`
+	BlockHeight uint64 `json:"blockHeight"`
+	ExtraInfo   string `json:"extraInfo"`
+}
+
+// errorResponse is the RFC7807 problem+json body ARC returns for non-2xx responses, carrying one of ARC's
+// structured status codes identifying why the submission was rejected
+type errorResponse struct {
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// ARC status codes (see the ARC OpenAPI spec) that this plugin maps onto typed sentinel errors, so that
+// orchestrator code can tell a transient rejection (worth retrying) from a permanent one (abort)
+const (
+	arcStatusMalformedTx     = 461
+	arcStatusMempoolConflict = 463
+	arcStatusFeeTooLow       = 465
+	arcStatusPolicyViolation = 466
+)
+
+// mapARCStatus maps one of ARC's structured error status codes onto a typed sentinel error, falling back to
+// genericErr (a generic i18n error, already carrying errRes.Detail) for anything not specifically recognised
+func mapARCStatus(errRes errorResponse, genericErr error) error {
+	switch errRes.Status {
+	case arcStatusMalformedTx:
+		return fmt.Errorf("%w: %s", blockchain.ErrMalformedTransaction, errRes.Detail)
+	case arcStatusMempoolConflict:
+		return fmt.Errorf("%w: %s", blockchain.ErrMempoolConflict, errRes.Detail)
+	case arcStatusFeeTooLow, arcStatusPolicyViolation:
+		return fmt.Errorf("%w: %s", blockchain.ErrPolicyViolation, errRes.Detail)
+	default:
+		return genericErr
+	}
+}
+
+// do sends req, retrying with exponential backoff on network errors and 5xx responses (which ARC's own docs
+// describe as retriable - a miner/agent outage rather than a rejection of the transaction itself). req is
+// rebuilt from freshReq on every attempt, since an *http.Request's body can only be read once.
+func (a *ARC) do(ctx context.Context, freshReq func() (*http.Request, error)) (int, []byte, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		req, err := freshReq()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		res, doErr := a.client.Do(req)
+		if doErr != nil {
+			lastErr = doErr
+		} else {
+			resBody, readErr := io.ReadAll(res.Body)
+			res.Body.Close()
+			if readErr != nil {
+				lastErr = readErr
+			} else if res.StatusCode < 500 {
+				return res.StatusCode, resBody, nil
+			} else if attempt >= a.maxRetries {
+				// retries exhausted on a server error - hand the response back to the caller to decode,
+				// rather than masking it behind a generic transport error
+				return res.StatusCode, resBody, nil
+			} else {
+				lastErr = fmt.Errorf("server error: %d", res.StatusCode)
+			}
+		}
+
+		if attempt >= a.maxRetries {
+			return 0, nil, lastErr
+		}
+		backoff := a.retryBase * time.Duration(1<<uint(attempt))
+		log.L(ctx).Debugf("ARC request failed (attempt %d/%d), retrying in %s: %v", attempt+1, a.maxRetries, backoff, lastErr)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+	}
+}
+
+func (a *ARC) SubmitBroadcastBatch(identity string, broadcast blockchain.BroadcastBatch, options *blockchain.SubmitOptions) (string, error) {
+	ctx := a.ctx
+	body, err := json.Marshal(&txSubmission{
+		RawTx: fmt.Sprintf("%x", broadcast.BatchPaylodRef),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	status, resBody, err := a.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/v1/tx", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if options != nil {
+			if options.WaitFor != "" {
+				req.Header.Set("X-WaitFor", string(options.WaitFor))
+			}
+			if options.CallbackURL != "" {
+				req.Header.Set("X-CallbackUrl", options.CallbackURL)
+			}
+		}
+		return req, nil
+	})
+	if err != nil {
+		return "", i18n.NewError(ctx, i18n.MsgBlockchainSubmitFailed, err)
+	}
+
+	if status >= 300 {
+		var errRes errorResponse
+		_ = json.Unmarshal(resBody, &errRes)
+		return "", mapARCStatus(errRes, i18n.NewError(ctx, i18n.MsgBlockchainSubmitFailed, errRes.Detail))
+	}
+
+	var txRes txResponse
+	if err := json.Unmarshal(resBody, &txRes); err != nil {
+		return "", i18n.NewError(ctx, i18n.MsgBlockchainSubmitFailed, err)
+	}
+
+	log.L(ctx).Debugf("ARC accepted tx %s identity=%s status=%s", txRes.TxID, identity, txRes.TxStatus)
+	return txRes.TxID, nil
+}
+
+func (a *ARC) QueryTransaction(ctx context.Context, txID string) (*blockchain.TransactionStatus, error) {
+	status, resBody, err := a.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, a.url+"/v1/tx/"+txID, nil)
+	})
+	if err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+
+	if status >= 300 {
+		var errRes errorResponse
+		_ = json.Unmarshal(resBody, &errRes)
+		return nil, mapARCStatus(errRes, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, errRes.Detail))
+	}
+
+	var txRes txResponse
+	if err := json.Unmarshal(resBody, &txRes); err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+
+	return &blockchain.TransactionStatus{
+		TxTrackingID: txRes.TxID,
+		State:        mapTxStatus(txRes.TxStatus),
+		BlockHeight:  txRes.BlockHeight,
+		AdditionalInfo: map[string]interface{}{
+			"arcStatus": txRes.TxStatus,
+			"extraInfo": txRes.ExtraInfo,
+		},
+	}, nil
+}
+
+func mapTxStatus(arcStatus string) blockchain.TransactionState {
+	switch arcStatus {
+	case "MINED", "CONFIRMED":
+		return blockchain.TransactionStateConfirmed
+	case "REJECTED", "SEEN_IN_ORPHAN_MEMPOOL":
+		return blockchain.TransactionStateFailed
+	default:
+		return blockchain.TransactionStateSubmitted
+	}
+}
+
+// policyResponse is the ARC /v1/policy response body
+type policyResponse struct {
+	MiningFee struct {
+		Satoshis int64 `json:"satoshis"`
+		Bytes    int64 `json:"bytes"`
+	} `json:"miningFee"`
+	RelayFee struct {
+		Satoshis int64 `json:"satoshis"`
+		Bytes    int64 `json:"bytes"`
+	} `json:"relayFee"`
+}
+
+func (a *ARC) GetPolicy(ctx context.Context) (*blockchain.Policy, error) {
+	status, resBody, err := a.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, a.url+"/v1/policy", nil)
+	})
+	if err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+
+	if status >= 300 {
+		var errRes errorResponse
+		_ = json.Unmarshal(resBody, &errRes)
+		return nil, mapARCStatus(errRes, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, errRes.Detail))
+	}
+
+	var p policyResponse
+	if err := json.Unmarshal(resBody, &p); err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+
+	feeRate := 0.0
+	if p.MiningFee.Bytes > 0 {
+		feeRate = float64(p.MiningFee.Satoshis) / float64(p.MiningFee.Bytes) * 1000
+	}
+	relayRate := 0.0
+	if p.RelayFee.Bytes > 0 {
+		relayRate = float64(p.RelayFee.Satoshis) / float64(p.RelayFee.Bytes) * 1000
+	}
+
+	return &blockchain.Policy{
+		FeeUnit:   "sat/kb",
+		MiningFee: feeRate,
+		RelayFee:  relayRate,
+	}, nil
+}
+
+// SubscribeEvents is not yet supported by ARC, which only exposes transaction submission/status endpoints,
+// not a general-purpose on-chain event feed
+func (a *ARC) SubscribeEvents(ctx context.Context, subscription blockchain.EventSubscription) (blockchain.SubscriptionID, error) {
+	return "", i18n.NewError(ctx, i18n.MsgBlockchainUnsupported, "SubscribeEvents", "arc")
+}
+
+// EstimateGas estimates the submission cost of batch in satoshis, using the currently advertised mining fee rate
+func (a *ARC) EstimateGas(ctx context.Context, batch blockchain.BroadcastBatch) (uint64, error) {
+	policy, err := a.GetPolicy(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// A batch pin is a single small OP_RETURN output - approximate its size rather than building the real
+	// transaction, as callers only need this for relative ranking/budgeting, not for constructing the tx itself
+	const estimatedTxBytes = 250
+	return uint64(policy.MiningFee * estimatedTxBytes / 1000), nil
+}
+
+// HandleCallback implements blockchain.CallbackHandler, processing the body ARC posts back to the
+// CallbackURL supplied via SubmitOptions.CallbackURL, and pushing the resulting state transition to
+// Events.TransactionUpdate rather than requiring the caller to keep polling QueryTransaction.
+func (a *ARC) HandleCallback(ctx context.Context, body []byte) error {
+	var cb txResponse
+	if err := json.Unmarshal(body, &cb); err != nil {
+		return i18n.NewError(ctx, i18n.MsgBlockchainCallbackInvalid, err)
+	}
+
+	state := mapTxStatus(cb.TxStatus)
+	errorMessage := ""
+	if state == blockchain.TransactionStateFailed {
+		errorMessage = cb.ExtraInfo
+	}
+
+	a.events.TransactionUpdate(cb.TxID, state, errorMessage, map[string]interface{}{
+		"arcStatus":   cb.TxStatus,
+		"blockHeight": cb.BlockHeight,
+		"extraInfo":   cb.ExtraInfo,
+	})
+	return nil
+}
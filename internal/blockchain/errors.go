@@ -0,0 +1,51 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blockchain
+
+import "errors"
+
+// Sentinel submission errors. Protocol-specific plugins map their own structured error codes onto these (by
+// wrapping one with fmt.Errorf("%w: ...", ...)) so that protocol-agnostic callers, such as the outbound batch
+// pool, can tell a transient condition worth retrying apart from a permanent rejection of the submission itself.
+var (
+	// ErrMempoolConflict means the submission conflicts with another transaction already in the mempool -
+	// expected to clear itself once that conflict resolves (the other transaction is mined or evicted)
+	ErrMempoolConflict = errors.New("submission conflicts with a transaction already in the mempool")
+
+	// ErrNonceGap means the submission was rejected because an earlier nonce for the same identity has not
+	// yet been observed - expected to clear itself once that earlier transaction lands (or is resubmitted)
+	ErrNonceGap = errors.New("submission has a gap before an earlier nonce")
+
+	// ErrMalformedTransaction means the submission itself was rejected as invalid and will never succeed unmodified
+	ErrMalformedTransaction = errors.New("submission is malformed")
+
+	// ErrPolicyViolation means the submission was rejected by the remote policy (fee too low, script checks,
+	// etc.) and will never succeed unless resubmitted with different parameters
+	ErrPolicyViolation = errors.New("submission violates policy")
+)
+
+// IsRetryable returns true if err (or anything it wraps) indicates a transient condition worth retrying,
+// rather than a permanent rejection of the submission itself
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrMempoolConflict) || errors.Is(err, ErrNonceGap)
+}
+
+// IsInvalid returns true if err (or anything it wraps) indicates the submission itself was rejected as
+// invalid, as opposed to merely failing to land this time around
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrMalformedTransaction) || errors.Is(err, ErrPolicyViolation)
+}
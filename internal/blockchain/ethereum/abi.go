@@ -0,0 +1,364 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+)
+
+// eventSignature renders event in its canonical Solidity form (e.g. "Transfer(address,address,uint256)"),
+// the input to Keccak256 when computing an event's topic0
+func eventSignature(event blockchain.ABIEvent) string {
+	types := make([]string, len(event.Inputs))
+	for i, p := range event.Inputs {
+		types[i] = canonicalType(p)
+	}
+	return event.Name + "(" + strings.Join(types, ",") + ")"
+}
+
+// canonicalType returns p's canonical Solidity type string, expanding "tuple" into its component types -
+// e.g. a tuple of (address,uint256) renders as "(address,uint256)". Arrays of tuples are not supported.
+func canonicalType(p blockchain.ABIParameter) string {
+	if p.Type == "tuple" {
+		parts := make([]string, len(p.Components))
+		for i, c := range p.Components {
+			parts[i] = canonicalType(c)
+		}
+		return "(" + strings.Join(parts, ",") + ")"
+	}
+	return p.Type
+}
+
+// topic0 is the first topic of every log emitted for event: keccak256(signature), identifying which event
+// fired (Solidity doesn't put the event name or a discriminator anywhere else in the log)
+func topic0(event blockchain.ABIEvent) [32]byte {
+	return Keccak256([]byte(eventSignature(event)))
+}
+
+var arrayTypeRE = regexp.MustCompile(`^(.*)\[(\d*)\]$`)
+
+// parseArrayType splits "T[]" or "T[N]" into its element type T and N (0 for a dynamic "T[]"), returning
+// ok=false if typ does not name an array at all
+func parseArrayType(typ string) (elemType string, length int, dynamic bool, ok bool) {
+	m := arrayTypeRE.FindStringSubmatch(typ)
+	if m == nil {
+		return "", 0, false, false
+	}
+	if m[2] == "" {
+		return m[1], 0, true, true
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, false, false
+	}
+	return m[1], n, false, true
+}
+
+// isDynamic returns true if p's ABI encoding is "dynamic" (length-prefixed, referenced via a 32-byte offset
+// from the enclosing head) rather than "static" (a fixed number of 32-byte words, inlined directly)
+func isDynamic(p blockchain.ABIParameter) bool {
+	switch {
+	case p.Type == "string" || p.Type == "bytes":
+		return true
+	case p.Type == "tuple":
+		for _, c := range p.Components {
+			if isDynamic(c) {
+				return true
+			}
+		}
+		return false
+	default:
+		if elemType, _, dynamic, ok := parseArrayType(p.Type); ok {
+			if dynamic {
+				return true
+			}
+			return isDynamic(blockchain.ABIParameter{Type: elemType, Components: p.Components})
+		}
+		return false
+	}
+}
+
+// staticWords returns the number of 32-byte words p's static encoding occupies. Only valid if !isDynamic(p).
+func staticWords(p blockchain.ABIParameter) int {
+	switch p.Type {
+	case "tuple":
+		words := 0
+		for _, c := range p.Components {
+			words += staticWords(c)
+		}
+		return words
+	default:
+		if elemType, length, _, ok := parseArrayType(p.Type); ok {
+			return length * staticWords(blockchain.ABIParameter{Type: elemType, Components: p.Components})
+		}
+		return 1
+	}
+}
+
+// decodeParams decodes the ordered tuple params from buf, which holds exactly that tuple's own ABI-encoded
+// region (head followed by tail) - used both for a whole event's non-indexed arguments, and recursively for
+// any nested dynamic tuple/array value
+func decodeParams(params []blockchain.ABIParameter, buf []byte) ([]interface{}, error) {
+	values := make([]interface{}, len(params))
+	cursor := 0
+	for i, p := range params {
+		if isDynamic(p) {
+			if cursor+32 > len(buf) {
+				return nil, fmt.Errorf("truncated abi data decoding offset for %s", p.Name)
+			}
+			offset := int(new(big.Int).SetBytes(buf[cursor : cursor+32]).Uint64())
+			if offset > len(buf) {
+				return nil, fmt.Errorf("offset out of range decoding %s", p.Name)
+			}
+			val, err := decodeValue(p, buf[offset:])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = val
+			cursor += 32
+		} else {
+			words := staticWords(p)
+			end := cursor + words*32
+			if end > len(buf) {
+				return nil, fmt.Errorf("truncated abi data decoding %s", p.Name)
+			}
+			val, err := decodeValue(p, buf[cursor:end])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = val
+			cursor = end
+		}
+	}
+	return values, nil
+}
+
+// decodeValue decodes a single value of type p from buf, which starts exactly at that value's own encoding
+func decodeValue(p blockchain.ABIParameter, buf []byte) (interface{}, error) {
+	switch p.Type {
+	case "bytes":
+		length, data, err := decodeLengthPrefixed(buf)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, data[:length]...), nil
+	case "string":
+		length, data, err := decodeLengthPrefixed(buf)
+		if err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	case "tuple":
+		sub, err := decodeParams(p.Components, buf)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, len(p.Components))
+		for i, c := range p.Components {
+			m[c.Name] = sub[i]
+		}
+		return m, nil
+	default:
+		if elemType, length, dynamic, ok := parseArrayType(p.Type); ok {
+			elem := blockchain.ABIParameter{Type: elemType, Components: p.Components}
+			if dynamic {
+				n, data, err := decodeLengthPrefixedRaw(buf)
+				if err != nil {
+					return nil, err
+				}
+				length = n
+				buf = data
+			}
+			elems := make([]blockchain.ABIParameter, length)
+			for i := range elems {
+				elems[i] = elem
+			}
+			return decodeParams(elems, buf)
+		}
+		return decodeScalar(p.Type, buf)
+	}
+}
+
+// decodeLengthPrefixed reads a dynamic bytes/string value: a 32-byte big-endian length, followed by that
+// many bytes of data (right-padded to a whole number of words, which callers ignore)
+func decodeLengthPrefixed(buf []byte) (length int, data []byte, err error) {
+	length, data, err = decodeLengthPrefixedRaw(buf)
+	if err != nil {
+		return 0, nil, err
+	}
+	if length > len(data) {
+		return 0, nil, fmt.Errorf("truncated abi data: length %d exceeds available %d bytes", length, len(data))
+	}
+	return length, data, nil
+}
+
+func decodeLengthPrefixedRaw(buf []byte) (int, []byte, error) {
+	if len(buf) < 32 {
+		return 0, nil, fmt.Errorf("truncated abi data reading length prefix")
+	}
+	length := int(new(big.Int).SetBytes(buf[0:32]).Uint64())
+	return length, buf[32:], nil
+}
+
+var intTypeRE = regexp.MustCompile(`^(u?int)(\d*)$`)
+
+// decodeScalar decodes a single-word (32 byte) static value: address, bool, (u)intN, or bytesN
+func decodeScalar(typ string, word []byte) (interface{}, error) {
+	if len(word) < 32 {
+		return nil, fmt.Errorf("truncated abi data decoding %s", typ)
+	}
+	word = word[:32]
+
+	switch {
+	case typ == "address":
+		return "0x" + fmt.Sprintf("%x", word[12:32]), nil
+	case typ == "bool":
+		return word[31] != 0, nil
+	case intTypeRE.MatchString(typ):
+		m := intTypeRE.FindStringSubmatch(typ)
+		signed := m[1] == "int"
+		v := new(big.Int).SetBytes(word)
+		if signed && word[0]&0x80 != 0 {
+			// two's complement negative: v - 2^256
+			modulus := new(big.Int).Lsh(big.NewInt(1), 256)
+			v.Sub(v, modulus)
+		}
+		return v, nil
+	case strings.HasPrefix(typ, "bytes"):
+		n, err := strconv.Atoi(strings.TrimPrefix(typ, "bytes"))
+		if err != nil || n < 1 || n > 32 {
+			return nil, fmt.Errorf("unsupported fixed bytes type %q", typ)
+		}
+		return append([]byte{}, word[:n]...), nil
+	default:
+		return nil, fmt.Errorf("unsupported abi type %q", typ)
+	}
+}
+
+// decodeIndexed decodes a single indexed argument from its 32-byte topic slot. Dynamic (and other
+// multi-word) indexed types are hashed by the node rather than included verbatim in the log, so only their
+// keccak256 digest is recoverable here - the raw topic is returned hex-encoded in that case.
+func decodeIndexed(p blockchain.ABIParameter, topic [32]byte) (interface{}, error) {
+	if isDynamic(p) || staticWords(p) != 1 {
+		return "0x" + fmt.Sprintf("%x", topic[:]), nil
+	}
+	return decodeScalar(p.Type, topic[:])
+}
+
+// decodeEventArgs decodes a single log's indexed topics and non-indexed data buffer into a name-keyed map,
+// per Solidity's ABI event encoding rules: topics[0] is topic0 (checked against topic0(event) by the
+// caller); topics[1:] correspond 1:1 to event.Inputs' indexed parameters in declaration order; data holds
+// the non-indexed parameters abi-encoded exactly as an implicit tuple (static types inline in the head,
+// dynamic types as a head offset into a length-prefixed tail).
+func decodeEventArgs(event blockchain.ABIEvent, topics [][32]byte, data []byte) (map[string]interface{}, error) {
+	outputs := make(map[string]interface{}, len(event.Inputs))
+
+	var nonIndexed []blockchain.ABIParameter
+	topicIdx := 1
+	for _, p := range event.Inputs {
+		if p.Indexed {
+			if topicIdx >= len(topics) {
+				return nil, fmt.Errorf("missing topic for indexed parameter %s", p.Name)
+			}
+			val, err := decodeIndexed(p, topics[topicIdx])
+			if err != nil {
+				return nil, err
+			}
+			outputs[p.Name] = val
+			topicIdx++
+		} else {
+			nonIndexed = append(nonIndexed, p)
+		}
+	}
+
+	values, err := decodeParams(nonIndexed, data)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range nonIndexed {
+		outputs[p.Name] = values[i]
+	}
+	return outputs, nil
+}
+
+// topicFilter returns the "topics" array to pass to eth_subscribe for subscription: topic0 fixed to this
+// event's signature hash, followed by one slot per indexed parameter - a literal 32-byte match value if
+// Filter supplies one for that parameter, or nil (wildcard, matching any value) otherwise.
+func topicFilter(subscription blockchain.EventSubscription) ([]interface{}, error) {
+	t0 := topic0(subscription.Event)
+	topics := []interface{}{"0x" + fmt.Sprintf("%x", t0[:])}
+
+	for _, p := range subscription.Event.Inputs {
+		if !p.Indexed {
+			continue
+		}
+		raw, filtered := subscription.Filter[p.Name]
+		if !filtered {
+			topics = append(topics, nil)
+			continue
+		}
+		word, err := encodeFilterValue(p, raw)
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, "0x"+fmt.Sprintf("%x", word))
+	}
+	return topics, nil
+}
+
+// encodeFilterValue encodes a single indexed-topic filter match value supplied via EventSubscription.Filter.
+// Only address parameters, given as a 0x-prefixed hex string, are supported; a filter value for any other
+// type (including the other static scalar types decodeIndexed can decode) returns an error.
+func encodeFilterValue(p blockchain.ABIParameter, raw interface{}) ([32]byte, error) {
+	var word [32]byte
+	switch v := raw.(type) {
+	case string:
+		if p.Type == "address" {
+			trimmed := strings.TrimPrefix(v, "0x")
+			b, err := hexDecode(trimmed)
+			if err != nil || len(b) != 20 {
+				return word, fmt.Errorf("invalid address filter value %q", v)
+			}
+			copy(word[12:], b)
+			return word, nil
+		}
+		return word, fmt.Errorf("unsupported filter value %q for type %s", v, p.Type)
+	default:
+		return word, fmt.Errorf("unsupported filter value type %T for %s", raw, p.Name)
+	}
+}
+
+func hexDecode(s string) ([]byte, error) {
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("odd-length hex string")
+	}
+	out := make([]byte, len(s)/2)
+	for i := range out {
+		var b byte
+		if _, err := fmt.Sscanf(s[i*2:i*2+2], "%02x", &b); err != nil {
+			return nil, err
+		}
+		out[i] = b
+	}
+	return out, nil
+}
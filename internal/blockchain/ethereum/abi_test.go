@@ -0,0 +1,187 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/stretchr/testify/assert"
+)
+
+// word32 left-pads v to a 32-byte big-endian word, the basic unit every abi-encoded value is built from
+func word32(v *big.Int) []byte {
+	b := make([]byte, 32)
+	v.FillBytes(b)
+	return b
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestTopic0MatchesKnownERC20TransferSignature(t *testing.T) {
+	event := blockchain.ABIEvent{
+		Name: "Transfer",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "from", Type: "address", Indexed: true},
+			{Name: "to", Type: "address", Indexed: true},
+			{Name: "value", Type: "uint256"},
+		},
+	}
+	topic := topic0(event)
+	assert.Equal(t, "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", fmt.Sprintf("%x", topic[:]))
+}
+
+func TestDecodeEventArgsDynamicBytes(t *testing.T) {
+	event := blockchain.ABIEvent{
+		Name: "Noted",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "id", Type: "uint256"},
+			{Name: "note", Type: "bytes"},
+		},
+	}
+
+	noteContent := []byte("hello firefly")
+	var data []byte
+	data = append(data, word32(big.NewInt(42))...)                      // id = 42 (static, inline)
+	data = append(data, word32(big.NewInt(64))...)                      // offset to note's length prefix
+	data = append(data, word32(big.NewInt(int64(len(noteContent))))...) // note length
+	padded := make([]byte, ((len(noteContent)+31)/32)*32)
+	copy(padded, noteContent)
+	data = append(data, padded...)
+
+	topics := [][32]byte{topic0(event)}
+	outputs, err := decodeEventArgs(event, topics, data)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), outputs["id"])
+	assert.Equal(t, noteContent, outputs["note"])
+}
+
+func TestDecodeEventArgsStaticArray(t *testing.T) {
+	event := blockchain.ABIEvent{
+		Name: "Scores",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "values", Type: "uint256[3]"},
+		},
+	}
+
+	var data []byte
+	data = append(data, word32(big.NewInt(10))...)
+	data = append(data, word32(big.NewInt(20))...)
+	data = append(data, word32(big.NewInt(30))...)
+
+	topics := [][32]byte{topic0(event)}
+	outputs, err := decodeEventArgs(event, topics, data)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{big.NewInt(10), big.NewInt(20), big.NewInt(30)}, outputs["values"])
+}
+
+func TestDecodeEventArgsDynamicArrayOfTuples(t *testing.T) {
+	// a tuple parameter whose fields are all static, wrapped in a dynamic array - exercises tuple
+	// decoding, array-length-prefix decoding, and the offset/tail split all at once
+	pointType := blockchain.ABIParameter{
+		Type: "tuple",
+		Components: []blockchain.ABIParameter{
+			{Name: "x", Type: "uint256"},
+			{Name: "y", Type: "uint256"},
+		},
+	}
+	event := blockchain.ABIEvent{
+		Name: "Path",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "points", Type: "tuple[]", Components: pointType.Components},
+		},
+	}
+
+	var tail []byte
+	tail = append(tail, word32(big.NewInt(2))...) // array length = 2
+	tail = append(tail, word32(big.NewInt(1))...) // points[0].x
+	tail = append(tail, word32(big.NewInt(2))...) // points[0].y
+	tail = append(tail, word32(big.NewInt(3))...) // points[1].x
+	tail = append(tail, word32(big.NewInt(4))...) // points[1].y
+
+	var data []byte
+	data = append(data, word32(big.NewInt(32))...) // offset to the array's length prefix
+	data = append(data, tail...)
+
+	topics := [][32]byte{topic0(event)}
+	outputs, err := decodeEventArgs(event, topics, data)
+	assert.NoError(t, err)
+
+	points := outputs["points"].([]interface{})
+	assert.Len(t, points, 2)
+	assert.Equal(t, big.NewInt(1), points[0].(map[string]interface{})["x"])
+	assert.Equal(t, big.NewInt(2), points[0].(map[string]interface{})["y"])
+	assert.Equal(t, big.NewInt(3), points[1].(map[string]interface{})["x"])
+	assert.Equal(t, big.NewInt(4), points[1].(map[string]interface{})["y"])
+}
+
+func TestDecodeEventArgsIndexedAndNonIndexedMixed(t *testing.T) {
+	event := blockchain.ABIEvent{
+		Name: "Transfer",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "from", Type: "address", Indexed: true},
+			{Name: "to", Type: "address", Indexed: true},
+			{Name: "value", Type: "uint256"},
+		},
+	}
+
+	var fromTopic, toTopic [32]byte
+	copy(fromTopic[12:], mustHex("1111111111111111111111111111111111111111"))
+	copy(toTopic[12:], mustHex("2222222222222222222222222222222222222222"))
+
+	data := word32(big.NewInt(500))
+
+	topics := [][32]byte{topic0(event), fromTopic, toTopic}
+	outputs, err := decodeEventArgs(event, topics, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x1111111111111111111111111111111111111111", outputs["from"])
+	assert.Equal(t, "0x2222222222222222222222222222222222222222", outputs["to"])
+	assert.Equal(t, big.NewInt(500), outputs["value"])
+}
+
+func TestDecodeEventArgsMissingTopicErrors(t *testing.T) {
+	event := blockchain.ABIEvent{
+		Name: "Transfer",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "from", Type: "address", Indexed: true},
+		},
+	}
+	_, err := decodeEventArgs(event, [][32]byte{topic0(event)}, nil)
+	assert.Error(t, err)
+}
+
+func TestEventSignatureExpandsTuple(t *testing.T) {
+	event := blockchain.ABIEvent{
+		Name: "Path",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "point", Type: "tuple", Components: []blockchain.ABIParameter{
+				{Name: "x", Type: "uint256"},
+				{Name: "y", Type: "uint256"},
+			}},
+		},
+	}
+	assert.Equal(t, "Path((uint256,uint256))", eventSignature(event))
+}
@@ -0,0 +1,109 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import "encoding/binary"
+
+// Keccak256 is the original (pre-NIST-finalization) Keccak-256 hash, as used throughout Ethereum for
+// event topics, function selectors and addresses. It is deliberately NOT the same as NIST SHA3-256 (which
+// uses a different padding suffix), so it's implemented directly here rather than relying on any "sha3"
+// package that only offers the standardized variant.
+func Keccak256(data []byte) [32]byte {
+	const rate = 136 // 1088-bit rate for a 256-bit capacity/output
+
+	padLen := rate - (len(data) % rate)
+	msg := make([]byte, len(data)+padLen)
+	copy(msg, data)
+	msg[len(data)] ^= 0x01 // Keccak's pad10*1, not SHA3's 0x06 domain suffix
+	msg[len(msg)-1] ^= 0x80
+
+	var state [25]uint64
+	for len(msg) > 0 {
+		block := msg[:rate]
+		msg = msg[rate:]
+		for i := 0; i < rate/8; i++ {
+			state[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+		}
+		keccakF1600(&state)
+	}
+
+	var out [32]byte
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], state[i])
+	}
+	return out
+}
+
+var roundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+func rotl64(x uint64, n uint) uint64 {
+	return (x << n) | (x >> (64 - n))
+}
+
+// keccakF1600 applies the 24-round Keccak-f[1600] permutation in place to the 5x5 lane state (a[x+5*y])
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	var d [5]uint64
+
+	for round := 0; round < 24; round++ {
+		// theta
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d[x] = c[(x+4)%5] ^ rotl64(c[(x+1)%5], 1)
+		}
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				a[x+5*y] ^= d[x]
+			}
+		}
+
+		// rho and pi, combined: walk the (x,y) -> (y, 2x+3y mod 5) orbit starting at (1,0), using the
+		// standard triangular-number rotation amounts that fall out of that traversal order
+		x, y := 1, 0
+		current := a[x+5*y]
+		for t := 0; t < 24; t++ {
+			newX, newY := y, (2*x+3*y)%5
+			rotAmt := uint(((t + 1) * (t + 2) / 2) % 64)
+			temp := a[newX+5*newY]
+			a[newX+5*newY] = rotl64(current, rotAmt)
+			current = temp
+			x, y = newX, newY
+		}
+
+		// chi
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 5; x++ {
+				c[x] = a[x+5*y]
+			}
+			for x := 0; x < 5; x++ {
+				a[x+5*y] = c[x] ^ ((^c[(x+1)%5]) & c[(x+2)%5])
+			}
+		}
+
+		// iota
+		a[0] ^= roundConstants[round]
+	}
+}
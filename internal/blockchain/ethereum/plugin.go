@@ -0,0 +1,433 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ethereum implements the blockchain.Plugin interface against an Ethereum-compatible JSON-RPC node,
+// submitting batches as plain transactions and listening for arbitrary contract events over an eth_subscribe
+// websocket subscription.
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/log"
+)
+
+const (
+	// ConfigRPCURL is the HTTP(S) JSON-RPC URL of the Ethereum node
+	ConfigRPCURL = "rpcURL"
+	// ConfigWSURL is the websocket JSON-RPC URL of the Ethereum node, used for eth_subscribe
+	ConfigWSURL = "wsURL"
+
+	defaultReconnectDelay = 5 * time.Second
+)
+
+// Config is the structure into which Ethereum plugin configuration is marshaled
+type Config struct {
+	RPCURL string `json:"rpcURL"`
+	WSURL  string `json:"wsURL"`
+}
+
+// Ethereum is a blockchain.Plugin implementation that talks to an Ethereum-compatible JSON-RPC node
+type Ethereum struct {
+	ctx    context.Context
+	rpcURL string
+	wsURL  string
+	client *http.Client
+	events blockchain.Events
+
+	mux           sync.Mutex
+	nextRPCID     int64
+	subscriptions map[blockchain.SubscriptionID]*subscription
+	wsConn        *websocket.Conn
+}
+
+// subscription is this plugin's bookkeeping for a single EventSubscription registered via SubscribeEvents,
+// keyed by the eth_subscribe subscription id the node hands back once the "logs" filter is accepted
+type subscription struct {
+	id           blockchain.SubscriptionID
+	ethSubID     string
+	subscription blockchain.EventSubscription
+}
+
+// New creates a new, uninitialized Ethereum plugin. Init must be called before use.
+func New() *Ethereum {
+	return &Ethereum{
+		client:        &http.Client{},
+		subscriptions: make(map[blockchain.SubscriptionID]*subscription),
+	}
+}
+
+func (e *Ethereum) ConfigInterface() interface{} {
+	return &Config{}
+}
+
+func (e *Ethereum) Init(ctx context.Context, conf interface{}, events blockchain.Events) (*blockchain.Capabilities, error) {
+	c, ok := conf.(*Config)
+	if !ok || c.RPCURL == "" || c.WSURL == "" {
+		return nil, i18n.NewError(ctx, i18n.MsgMissingPluginConfig, ConfigRPCURL+"/"+ConfigWSURL, "blockchain.ethereum")
+	}
+
+	e.ctx = ctx
+	e.rpcURL = c.RPCURL
+	e.wsURL = c.WSURL
+	e.events = events
+
+	if err := e.connect(e.ctx); err != nil {
+		return nil, err
+	}
+	go e.eventLoop()
+
+	return &blockchain.Capabilities{
+		GlobalSequencer: true,
+	}, nil
+}
+
+// rpcRequest/rpcResponse are the standard JSON-RPC 2.0 envelope shapes used over both the HTTP and
+// websocket transports
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int64         `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// call invokes method over the HTTP JSON-RPC endpoint and unmarshals the result into result (if non-nil)
+func (e *Ethereum) call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	id := e.nextRequestID()
+	body, err := json.Marshal(&rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+	defer res.Body.Close()
+
+	var rpcRes rpcResponse
+	if err := json.NewDecoder(res.Body).Decode(&rpcRes); err != nil {
+		return i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+	if rpcRes.Error != nil {
+		return i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, rpcRes.Error.Message)
+	}
+	if result != nil {
+		return json.Unmarshal(rpcRes.Result, result)
+	}
+	return nil
+}
+
+func (e *Ethereum) nextRequestID() int64 {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.nextRPCID++
+	return e.nextRPCID
+}
+
+// SubmitBroadcastBatch submits broadcast.BatchPaylodRef as the data payload of a plain transaction (the
+// batch pin itself is opaque to this plugin - it's just bytes to include), returning the resulting tx hash
+// as the tracking ID.
+func (e *Ethereum) SubmitBroadcastBatch(identity string, broadcast blockchain.BroadcastBatch, options *blockchain.SubmitOptions) (string, error) {
+	ctx := e.ctx
+	var txHash string
+	err := e.call(ctx, "eth_sendTransaction", &txHash, map[string]interface{}{
+		"from": identity,
+		"data": "0x" + fmt.Sprintf("%x", broadcast.BatchPaylodRef),
+	})
+	if err != nil {
+		return "", i18n.NewError(ctx, i18n.MsgBlockchainSubmitFailed, err)
+	}
+	return txHash, nil
+}
+
+// txReceipt is the subset of an eth_getTransactionReceipt result this plugin cares about
+type txReceipt struct {
+	BlockNumber string `json:"blockNumber"`
+	Status      string `json:"status"`
+}
+
+func (e *Ethereum) QueryTransaction(ctx context.Context, txTrackingID string) (*blockchain.TransactionStatus, error) {
+	var receipt *txReceipt
+	if err := e.call(ctx, "eth_getTransactionReceipt", &receipt, txTrackingID); err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+
+	if receipt == nil {
+		return &blockchain.TransactionStatus{
+			TxTrackingID: txTrackingID,
+			State:        blockchain.TransactionStateSubmitted,
+		}, nil
+	}
+
+	blockNumber, _ := strconv.ParseUint(strings.TrimPrefix(receipt.BlockNumber, "0x"), 16, 64)
+	state := blockchain.TransactionStateConfirmed
+	if receipt.Status == "0x0" {
+		state = blockchain.TransactionStateFailed
+	}
+
+	return &blockchain.TransactionStatus{
+		TxTrackingID: txTrackingID,
+		State:        state,
+		BlockHeight:  blockNumber,
+	}, nil
+}
+
+// GetPolicy returns the node's currently advertised gas price as the fee rate, since Ethereum (pre-1559)
+// has no separate concept of a minimum relay fee
+func (e *Ethereum) GetPolicy(ctx context.Context) (*blockchain.Policy, error) {
+	var gasPriceHex string
+	if err := e.call(ctx, "eth_gasPrice", &gasPriceHex); err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+	gasPrice, _ := strconv.ParseUint(strings.TrimPrefix(gasPriceHex, "0x"), 16, 64)
+
+	return &blockchain.Policy{
+		FeeUnit:   "wei/gas",
+		MiningFee: float64(gasPrice),
+		RelayFee:  float64(gasPrice),
+	}, nil
+}
+
+// EstimateGas calls eth_estimateGas for the equivalent transaction SubmitBroadcastBatch would send
+func (e *Ethereum) EstimateGas(ctx context.Context, batch blockchain.BroadcastBatch) (uint64, error) {
+	var gasHex string
+	err := e.call(ctx, "eth_estimateGas", &gasHex, map[string]interface{}{
+		"data": "0x" + fmt.Sprintf("%x", batch.BatchPaylodRef),
+	})
+	if err != nil {
+		return 0, i18n.NewError(ctx, i18n.MsgBlockchainQueryFailed, err)
+	}
+	gas, _ := strconv.ParseUint(strings.TrimPrefix(gasHex, "0x"), 16, 64)
+	return gas, nil
+}
+
+// SubscribeEvents opens an eth_subscribe "logs" subscription matching subscription's contract address and
+// event topics, and registers it for dispatch to blockchain.Events.ContractEvent as matching logs arrive.
+func (e *Ethereum) SubscribeEvents(ctx context.Context, sub blockchain.EventSubscription) (blockchain.SubscriptionID, error) {
+	topics, err := topicFilter(sub)
+	if err != nil {
+		return "", i18n.NewError(ctx, i18n.MsgBlockchainSubscribeFailed, err)
+	}
+
+	filter := map[string]interface{}{"topics": topics}
+	if addr, ok := sub.Location["address"]; ok {
+		filter["address"] = addr
+	}
+	if sub.FromBlock != "" {
+		filter["fromBlock"] = sub.FromBlock
+	}
+
+	var ethSubID string
+	if err := e.call(ctx, "eth_subscribe", &ethSubID, "logs", filter); err != nil {
+		return "", i18n.NewError(ctx, i18n.MsgBlockchainSubscribeFailed, err)
+	}
+
+	id := blockchain.SubscriptionID(ethSubID)
+	e.mux.Lock()
+	e.subscriptions[id] = &subscription{id: id, ethSubID: ethSubID, subscription: sub}
+	e.mux.Unlock()
+
+	return id, nil
+}
+
+// connect dials the websocket JSON-RPC endpoint used for eth_subscribe notifications
+func (e *Ethereum) connect(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, e.wsURL, nil)
+	if err != nil {
+		return i18n.NewError(ctx, i18n.MsgBlockchainConnectFailed, err)
+	}
+	e.mux.Lock()
+	e.wsConn = conn
+	e.mux.Unlock()
+	return nil
+}
+
+// ethSubscriptionNotification is the shape of an unsolicited "eth_subscription" message the node pushes
+// over the websocket for every log matching a subscription previously registered via eth_subscribe
+type ethSubscriptionNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Subscription string `json:"subscription"`
+		Result       ethLog `json:"result"`
+	} `json:"params"`
+}
+
+// ethLog is a single entry as returned by eth_getLogs / delivered via an eth_subscribe "logs" notification
+type ethLog struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+	BlockNumber string   `json:"blockNumber"`
+	TxHash      string   `json:"transactionHash"`
+	LogIndex    string   `json:"logIndex"`
+}
+
+// eventLoop reads eth_subscription notifications off the websocket connection for as long as the plugin is
+// running, decoding and dispatching each one to Events.ContractEvent, and transparently reconnecting (and
+// re-registering every live subscription) if the connection drops.
+func (e *Ethereum) eventLoop() {
+	for {
+		e.mux.Lock()
+		conn := e.wsConn
+		e.mux.Unlock()
+
+		if conn == nil {
+			if e.ctx.Err() != nil {
+				return
+			}
+			if err := e.connect(e.ctx); err != nil {
+				log.L(e.ctx).Errorf("ethereum websocket reconnect failed, retrying in %s: %v", defaultReconnectDelay, err)
+				select {
+				case <-time.After(defaultReconnectDelay):
+				case <-e.ctx.Done():
+					return
+				}
+				continue
+			}
+			e.resubscribeAll()
+			continue
+		}
+
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if e.ctx.Err() != nil {
+				return
+			}
+			log.L(e.ctx).Errorf("ethereum websocket read failed, reconnecting: %v", err)
+			e.mux.Lock()
+			e.wsConn = nil
+			e.mux.Unlock()
+			continue
+		}
+
+		e.dispatchNotification(msg)
+	}
+}
+
+// resubscribeAll re-issues eth_subscribe for every subscription registered before a reconnect, since the
+// node has no memory of a dropped connection's filters
+func (e *Ethereum) resubscribeAll() {
+	e.mux.Lock()
+	subs := make([]*subscription, 0, len(e.subscriptions))
+	for _, s := range e.subscriptions {
+		subs = append(subs, s)
+	}
+	e.mux.Unlock()
+
+	for _, s := range subs {
+		if _, err := e.SubscribeEvents(e.ctx, s.subscription); err != nil {
+			log.L(e.ctx).Errorf("failed to re-subscribe after reconnect: %v", err)
+		}
+	}
+}
+
+// dispatchNotification decodes a single websocket message and, if it's an eth_subscription notification
+// matching a live subscription, decodes its log and delivers it via Events.ContractEvent
+func (e *Ethereum) dispatchNotification(msg []byte) {
+	var notification ethSubscriptionNotification
+	if err := json.Unmarshal(msg, &notification); err != nil || notification.Method != "eth_subscription" {
+		return
+	}
+
+	e.mux.Lock()
+	var match *subscription
+	for _, s := range e.subscriptions {
+		if s.ethSubID == notification.Params.Subscription {
+			match = s
+			break
+		}
+	}
+	e.mux.Unlock()
+	if match == nil {
+		return
+	}
+
+	contractEvent, err := e.decodeLog(match.subscription, notification.Params.Result)
+	if err != nil {
+		log.L(e.ctx).Errorf("failed to decode event log for subscription %s: %v", match.id, err)
+		return
+	}
+	e.events.ContractEvent(match.id, *contractEvent)
+}
+
+// decodeLog converts a raw JSON-RPC log entry into a blockchain.ContractEvent, using sub.Event's ABI shape
+// to decode both the indexed topics and the non-indexed data
+func (e *Ethereum) decodeLog(sub blockchain.EventSubscription, raw ethLog) (*blockchain.ContractEvent, error) {
+	topics := make([][32]byte, len(raw.Topics))
+	for i, t := range raw.Topics {
+		b, err := hexDecode(strings.TrimPrefix(t, "0x"))
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("malformed topic %q", t)
+		}
+		copy(topics[i][:], b)
+	}
+
+	expectedTopic0 := topic0(sub.Event)
+	if len(topics) == 0 || topics[0] != expectedTopic0 {
+		return nil, fmt.Errorf("log topic0 does not match subscribed event %s", sub.Event.Name)
+	}
+
+	data, err := hexDecode(strings.TrimPrefix(raw.Data, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("malformed data %q", raw.Data)
+	}
+
+	outputs, err := decodeEventArgs(sub.Event, topics, data)
+	if err != nil {
+		return nil, err
+	}
+
+	blockNumber, _ := strconv.ParseUint(strings.TrimPrefix(raw.BlockNumber, "0x"), 16, 64)
+	return &blockchain.ContractEvent{
+		Name:        sub.Event.Name,
+		Outputs:     outputs,
+		BlockNumber: blockNumber,
+		AdditionalInfo: map[string]interface{}{
+			"address":         raw.Address,
+			"transactionHash": raw.TxHash,
+			"logIndex":        raw.LogIndex,
+		},
+	}, nil
+}
@@ -0,0 +1,225 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEvents is a minimal blockchain.Events double, since there is no generated eventsmocks package for
+// this interface (mirrors the arc package's own test double)
+type fakeEvents struct {
+	contractEvents []blockchain.ContractEvent
+}
+
+func (f *fakeEvents) TransactionUpdate(txTrackingID string, txState blockchain.TransactionState, errorMessage string, additionalInfo map[string]interface{}) {
+}
+
+func (f *fakeEvents) SequencedBroadcastBatch(batch blockchain.BroadcastBatch, additionalInfo map[string]interface{}) {
+}
+
+func (f *fakeEvents) ContractEvent(subID blockchain.SubscriptionID, event blockchain.ContractEvent) {
+	f.contractEvents = append(f.contractEvents, event)
+}
+
+// jsonRPCHandler returns an httptest.Server that replies to method with result, ignoring params, mimicking
+// the minimum viable behaviour of an Ethereum JSON-RPC node for a single request/response round trip
+func jsonRPCHandler(t *testing.T, method string, result interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, method, req.Method)
+
+		resultBytes, err := json.Marshal(result)
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&rpcResponse{ID: req.ID, Result: resultBytes})
+	}))
+}
+
+func TestInitMissingConfig(t *testing.T) {
+	e := New()
+	_, err := e.Init(context.Background(), &Config{}, &fakeEvents{})
+	assert.Error(t, err)
+}
+
+func TestInitWrongConfigType(t *testing.T) {
+	e := New()
+	_, err := e.Init(context.Background(), "not a config", &fakeEvents{})
+	assert.Error(t, err)
+}
+
+func TestGetPolicyRoundTrip(t *testing.T) {
+	server := jsonRPCHandler(t, "eth_gasPrice", "0x3b9aca00") // 1 gwei
+	defer server.Close()
+
+	e := New()
+	e.ctx = context.Background()
+	e.rpcURL = server.URL
+	e.client = server.Client()
+
+	policy, err := e.GetPolicy(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1000000000), policy.MiningFee)
+	assert.Equal(t, "wei/gas", policy.FeeUnit)
+}
+
+func TestEstimateGasRoundTrip(t *testing.T) {
+	server := jsonRPCHandler(t, "eth_estimateGas", "0x5208") // 21000
+	defer server.Close()
+
+	e := New()
+	e.ctx = context.Background()
+	e.rpcURL = server.URL
+	e.client = server.Client()
+
+	gas, err := e.EstimateGas(context.Background(), blockchain.BroadcastBatch{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(21000), gas)
+}
+
+func TestQueryTransactionNotYetMined(t *testing.T) {
+	server := jsonRPCHandler(t, "eth_getTransactionReceipt", nil)
+	defer server.Close()
+
+	e := New()
+	e.ctx = context.Background()
+	e.rpcURL = server.URL
+	e.client = server.Client()
+
+	status, err := e.QueryTransaction(context.Background(), "0xabc")
+	assert.NoError(t, err)
+	assert.Equal(t, blockchain.TransactionStateSubmitted, status.State)
+}
+
+func TestQueryTransactionConfirmed(t *testing.T) {
+	server := jsonRPCHandler(t, "eth_getTransactionReceipt", &txReceipt{BlockNumber: "0x10", Status: "0x1"})
+	defer server.Close()
+
+	e := New()
+	e.ctx = context.Background()
+	e.rpcURL = server.URL
+	e.client = server.Client()
+
+	status, err := e.QueryTransaction(context.Background(), "0xabc")
+	assert.NoError(t, err)
+	assert.Equal(t, blockchain.TransactionStateConfirmed, status.State)
+	assert.Equal(t, uint64(16), status.BlockHeight)
+}
+
+func TestQueryTransactionReverted(t *testing.T) {
+	server := jsonRPCHandler(t, "eth_getTransactionReceipt", &txReceipt{BlockNumber: "0x10", Status: "0x0"})
+	defer server.Close()
+
+	e := New()
+	e.ctx = context.Background()
+	e.rpcURL = server.URL
+	e.client = server.Client()
+
+	status, err := e.QueryTransaction(context.Background(), "0xabc")
+	assert.NoError(t, err)
+	assert.Equal(t, blockchain.TransactionStateFailed, status.State)
+}
+
+func TestDispatchNotificationDecodesAndDelivers(t *testing.T) {
+	event := blockchain.ABIEvent{
+		Name: "Transfer",
+		Inputs: []blockchain.ABIParameter{
+			{Name: "from", Type: "address", Indexed: true},
+			{Name: "value", Type: "uint256"},
+		},
+	}
+
+	events := &fakeEvents{}
+	e := New()
+	e.ctx = context.Background()
+	e.events = events
+
+	subID := blockchain.SubscriptionID("0xsub1")
+	e.subscriptions[subID] = &subscription{
+		id:       subID,
+		ethSubID: "0xsub1",
+		subscription: blockchain.EventSubscription{
+			Event: event,
+		},
+	}
+
+	t0 := topic0(event)
+	value := word32BigEndian(500)
+
+	notification := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "eth_subscription",
+		"params": map[string]interface{}{
+			"subscription": "0xsub1",
+			"result": map[string]interface{}{
+				"address":         "0xcontract",
+				"topics":          []string{"0x" + hexString(t0[:]), "0x000000000000000000000000" + "1111111111111111111111111111111111111111"},
+				"data":            "0x" + hexString(value),
+				"blockNumber":     "0x64",
+				"transactionHash": "0xtxhash",
+				"logIndex":        "0x0",
+			},
+		},
+	}
+	msg, err := json.Marshal(notification)
+	assert.NoError(t, err)
+
+	e.dispatchNotification(msg)
+
+	assert.Len(t, events.contractEvents, 1)
+	assert.Equal(t, "Transfer", events.contractEvents[0].Name)
+	assert.Equal(t, "0x1111111111111111111111111111111111111111", events.contractEvents[0].Outputs["from"])
+	assert.Equal(t, uint64(100), events.contractEvents[0].BlockNumber)
+}
+
+func TestDispatchNotificationUnknownSubscriptionIgnored(t *testing.T) {
+	events := &fakeEvents{}
+	e := New()
+	e.ctx = context.Background()
+	e.events = events
+
+	msg := []byte(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xunknown","result":{}}}`)
+	e.dispatchNotification(msg)
+
+	assert.Empty(t, events.contractEvents)
+}
+
+func word32BigEndian(v uint64) []byte {
+	b := make([]byte, 32)
+	for i := 0; i < 8; i++ {
+		b[31-i] = byte(v >> (8 * i))
+	}
+	return b
+}
+
+func hexString(b []byte) string {
+	const hextable = "0123456789abcdef"
+	out := make([]byte, len(b)*2)
+	for i, v := range b {
+		out[i*2] = hextable[v>>4]
+		out[i*2+1] = hextable[v&0x0f]
+	}
+	return string(out)
+}
@@ -30,7 +30,133 @@ type Plugin interface {
 
 	// SubmitBroadcastBatch sequences a broadcast globally to all viewers of the blockchain
 	// The returned tracking ID will be used to correlate with any subsequent transaction tracking updates
-	SubmitBroadcastBatch(identity string, broadcast BroadcastBatch) (txTrackingID string, err error)
+	// options may be nil, in which case the plugin should apply its own sensible defaults
+	SubmitBroadcastBatch(identity string, broadcast BroadcastBatch, options *SubmitOptions) (txTrackingID string, err error)
+
+	// QueryTransaction polls the blockchain for the current state of a previously submitted transaction
+	QueryTransaction(ctx context.Context, txTrackingID string) (*TransactionStatus, error)
+
+	// GetPolicy returns the current fee/submission policy advertised by the blockchain (or its agent), so that
+	// callers can make informed choices about things like fee units before submitting a batch
+	GetPolicy(ctx context.Context) (*Policy, error)
+
+	// SubscribeEvents registers interest in an arbitrary on-chain event described by subscription, so that
+	// matching occurrences are delivered back to firefly via Events.ContractEvent. The returned SubscriptionID
+	// can be used to correlate later ContractEvent callbacks.
+	SubscribeEvents(ctx context.Context, subscription EventSubscription) (SubscriptionID, error)
+
+	// EstimateGas returns the plugin's best estimate of the gas/fee cost of submitting batch, for use by
+	// callers (such as the outbound batch pool) that need to rank or budget submissions before they happen
+	EstimateGas(ctx context.Context, batch BroadcastBatch) (uint64, error)
+}
+
+// CallbackHandler is an optional interface a Plugin can implement if its remote agent reports asynchronous
+// transaction status via an out-of-band HTTP callback (requested via SubmitOptions.CallbackURL) rather than,
+// or in addition to, active polling via QueryTransaction. The API server routes the callback body straight
+// through to HandleCallback, which is responsible for pushing any resulting state change to Events.TransactionUpdate.
+type CallbackHandler interface {
+	// HandleCallback processes a single raw callback body previously requested via SubmitOptions.CallbackURL
+	HandleCallback(ctx context.Context, body []byte) error
+}
+
+// SubscriptionID identifies a previously-registered EventSubscription
+type SubscriptionID string
+
+// EventSubscription describes an arbitrary on-chain event an app wants to be notified of, defined purely in
+// terms of its ABI shape so it applies equally to any contract that emits a matching event
+type EventSubscription struct {
+	// Location is plugin-specific addressing info for where to watch (e.g. a contract address)
+	Location map[string]interface{}
+
+	// Event is the ABI fragment describing the event to watch for
+	Event ABIEvent
+
+	// FromBlock is the point to start watching from ("0", "latest", or a protocol-specific block reference).
+	// Empty means the plugin's default (usually "latest")
+	FromBlock string
+
+	// Filter restricts delivery to occurrences whose indexed parameters match the given values, keyed by
+	// parameter name. Only indexed (topic) parameters can be filtered this way.
+	Filter map[string]interface{}
+}
+
+// ABIEvent is the minimal description of an event needed to generate a strongly-typed filter for it,
+// independent of any particular contract - just the event's name and its parameter list.
+type ABIEvent struct {
+	// Name is the event name, as declared in the contract source (e.g. "Transfer")
+	Name string
+
+	// Inputs is the ordered list of event parameters, each flagged as indexed or not
+	Inputs []ABIParameter
+}
+
+// ABIParameter describes a single parameter of an ABIEvent
+type ABIParameter struct {
+	// Name is the parameter name
+	Name string
+
+	// Type is the protocol-specific type string (e.g. "address", "uint256")
+	Type string
+
+	// Indexed is true if this parameter is one of the event's indexed (topic) fields
+	Indexed bool
+
+	// Components describes the ordered fields of a "tuple" parameter (ignored for all other types)
+	Components []ABIParameter
+}
+
+// WaitFor describes the point in a transaction's lifecycle a synchronous submission call should block until,
+// mirroring the waitFor semantics offered by ARC-style transaction processors
+type WaitFor string
+
+const (
+	// WaitForReceived the transaction processor has accepted the transaction, but not yet validated it
+	WaitForReceived WaitFor = "RECEIVED"
+	// WaitForStored the transaction has been persisted by the transaction processor
+	WaitForStored WaitFor = "STORED"
+	// WaitForSeenOnNetwork the transaction has been observed propagating on the peer-to-peer network
+	WaitForSeenOnNetwork WaitFor = "SEEN_ON_NETWORK"
+	// WaitForMined the transaction has been included in a block
+	WaitForMined WaitFor = "MINED"
+)
+
+// SubmitOptions are per-submission overrides a caller can supply to SubmitBroadcastBatch, alongside the
+// protocol-agnostic BroadcastBatch payload itself
+type SubmitOptions struct {
+	// WaitFor is the point in the transaction lifecycle to block until, before returning the tracking ID.
+	// If empty, the plugin picks its own default (usually the cheapest option it can reliably report on)
+	WaitFor WaitFor
+
+	// CallbackURL, if set, is passed to the blockchain agent so that it can push asynchronous status
+	// updates back to firefly out-of-band, rather than relying solely on polling via QueryTransaction
+	CallbackURL string
+}
+
+// TransactionStatus is the result of polling the blockchain (or its agent) for the state of a submitted transaction
+type TransactionStatus struct {
+	// TxTrackingID is the tracking ID this status relates to
+	TxTrackingID string
+
+	// State is the architecturally significant state of the transaction
+	State TransactionState
+
+	// BlockHeight is the height of the block the transaction was mined into, if known (zero otherwise)
+	BlockHeight uint64
+
+	// AdditionalInfo is opaque protocol-specific detail (competing tx IDs, merkle proof, etc.)
+	AdditionalInfo map[string]interface{}
+}
+
+// Policy is the fee/submission policy currently advertised by the blockchain (or its agent)
+type Policy struct {
+	// FeeUnit is the denomination the fee rates are expressed in, e.g. "sat/kb" or "sat/byte"
+	FeeUnit string
+
+	// MiningFee is the fee rate a miner currently expects in order to include a transaction in a block
+	MiningFee float64
+
+	// RelayFee is the minimum fee rate a transaction must carry in order to be relayed at all
+	RelayFee float64
 }
 
 // BlockchainEvents is the interface provided to the blockchain plugin, to allow it to pass events back to firefly.
@@ -55,6 +181,26 @@ type Events interface {
 	// submitted by us, or by any other authorized party in the network.
 	// additionalInfo can be used to add opaque protocol specific JSON from the plugin (block numbers etc.)
 	SequencedBroadcastBatch(batch BroadcastBatch, additionalInfo map[string]interface{})
+
+	// ContractEvent notifies on the arrival of an event matching a subscription previously registered via
+	// Plugin.SubscribeEvents. Unlike SequencedBroadcastBatch, these events are app-defined (not firefly's own
+	// batch pins), so delivery ordering is only guaranteed within a single subscription, not across subscriptions.
+	ContractEvent(subID SubscriptionID, event ContractEvent)
+}
+
+// ContractEvent is a single occurrence of an on-chain event matching a registered EventSubscription
+type ContractEvent struct {
+	// Name is the event name, as declared in the originating EventSubscription.Event
+	Name string
+
+	// Outputs are the decoded event parameters, keyed by ABIParameter name
+	Outputs map[string]interface{}
+
+	// BlockNumber is the block the event was emitted in
+	BlockNumber uint64
+
+	// AdditionalInfo is opaque protocol-specific detail (transaction hash, log index, etc.)
+	AdditionalInfo map[string]interface{}
 }
 
 // BlockchainCapabilities the supported featureset of the blockchain
@@ -63,6 +209,11 @@ type Capabilities struct {
 	// GlobalSequencer means submitting an ordered piece of data visible to all
 	// participants of the network (requires an all-participant chain)
 	GlobalSequencer bool
+
+	// CrossChainAnchor means the plugin emits the header/tx-inclusion proof alongside SequencedBroadcastBatch
+	// that the interop package needs to pin a batch sequenced on this chain onto another one (see
+	// internal/interop.RelayPlugin)
+	CrossChainAnchor bool
 }
 
 // TransactionState is the only architecturally significant thing that Firefly tracks on blockchain transactions.
@@ -91,4 +242,4 @@ type BroadcastBatch struct {
 
 	// BatchID is the id of the batch - writing this in plain text to the blockchain makes for easy correlation on-chain/off-chain
 	BatchID Bytes32
-}
\ No newline at end of file
+}
@@ -0,0 +1,89 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interop
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/log"
+)
+
+// Manager fans a single BroadcastBatch pin out across every configured relay chain, and resolves
+// a pin seen on any one of those chains back to the BroadcastBatch it anchors.
+type Manager interface {
+	// PinAll registers an anchor for batch, sourced from sourceChain, on every other configured relay chain
+	PinAll(ctx context.Context, sourceChain string, batch blockchain.BroadcastBatch, proof AnchorProof) error
+
+	// Resolve verifies and resolves a BatchID pinned on destChain back to its BroadcastBatch
+	Resolve(ctx context.Context, destChain string, batchID blockchain.Bytes32) (*blockchain.BroadcastBatch, error)
+}
+
+type interopManager struct {
+	ctx    context.Context
+	plugin map[string]RelayPlugin
+}
+
+// NewInteropManager constructs a Manager from a set of already-initialized relay plugins, keyed by chain name
+func NewInteropManager(ctx context.Context, plugins map[string]RelayPlugin) (Manager, error) {
+	if len(plugins) == 0 {
+		return nil, i18n.NewError(ctx, i18n.MsgInteropNoPlugins)
+	}
+	return &interopManager{
+		ctx:    ctx,
+		plugin: plugins,
+	}, nil
+}
+
+func (im *interopManager) PinAll(ctx context.Context, sourceChain string, batch blockchain.BroadcastBatch, proof AnchorProof) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(im.plugin))
+	for chain, plugin := range im.plugin {
+		if chain == sourceChain {
+			// no need to anchor a chain's own batch back onto itself
+			continue
+		}
+		wg.Add(1)
+		go func(chain string, plugin RelayPlugin) {
+			defer wg.Done()
+			if err := plugin.RegisterAnchor(ctx, sourceChain, batch, proof); err != nil {
+				log.L(ctx).Errorf("Failed to pin batch %s on chain %s: %s", batch.BatchID, chain, err)
+				errs <- err
+				return
+			}
+			log.L(ctx).Debugf("Pinned batch %s from %s onto %s", batch.BatchID, sourceChain, chain)
+		}(chain, plugin)
+	}
+	wg.Wait()
+	close(errs)
+	// Report the first failure - partial success is logged above but otherwise left for the caller to retry,
+	// same as the batch manager's existing at-least-once retry semantics for blockchain submission
+	for err := range errs {
+		return err
+	}
+	return nil
+}
+
+func (im *interopManager) Resolve(ctx context.Context, destChain string, batchID blockchain.Bytes32) (*blockchain.BroadcastBatch, error) {
+	plugin, ok := im.plugin[destChain]
+	if !ok {
+		return nil, i18n.NewError(ctx, i18n.MsgInteropUnknownChain, destChain)
+	}
+	return plugin.VerifyAnchor(ctx, destChain, batchID)
+}
@@ -0,0 +1,104 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interop
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRelay is a minimal RelayPlugin double recording calls, since there is no generated interopmocks
+// package for RelayPlugin (only for Manager, which is what the API server consumes)
+type fakeRelay struct {
+	chain       string
+	registerErr error
+
+	mux        sync.Mutex
+	registered []string
+}
+
+func (f *fakeRelay) Chain() string { return f.chain }
+
+func (f *fakeRelay) RegisterAnchor(ctx context.Context, sourceChain string, batch blockchain.BroadcastBatch, proof AnchorProof) error {
+	if f.registerErr != nil {
+		return f.registerErr
+	}
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.registered = append(f.registered, sourceChain)
+	return nil
+}
+
+func (f *fakeRelay) VerifyAnchor(ctx context.Context, destChain string, batchID blockchain.Bytes32) (*blockchain.BroadcastBatch, error) {
+	return &blockchain.BroadcastBatch{BatchID: batchID}, nil
+}
+
+func TestNewInteropManagerRequiresPlugins(t *testing.T) {
+	_, err := NewInteropManager(context.Background(), map[string]RelayPlugin{})
+	assert.Error(t, err)
+}
+
+func TestPinAllSkipsSourceChain(t *testing.T) {
+	chainA := &fakeRelay{chain: "chainA"}
+	chainB := &fakeRelay{chain: "chainB"}
+	m, err := NewInteropManager(context.Background(), map[string]RelayPlugin{"chainA": chainA, "chainB": chainB})
+	assert.NoError(t, err)
+
+	err = m.PinAll(context.Background(), "chainA", blockchain.BroadcastBatch{}, AnchorProof{})
+	assert.NoError(t, err)
+
+	assert.Empty(t, chainA.registered)
+	assert.Equal(t, []string{"chainA"}, chainB.registered)
+}
+
+func TestPinAllPartialFailureReportsError(t *testing.T) {
+	chainA := &fakeRelay{chain: "chainA"}
+	chainB := &fakeRelay{chain: "chainB", registerErr: errors.New("relay unreachable")}
+	m, err := NewInteropManager(context.Background(), map[string]RelayPlugin{"chainA": chainA, "chainB": chainB})
+	assert.NoError(t, err)
+
+	// pin a batch sourced on a third chain, so both chainA and chainB are attempted; chainB fails but
+	// chainA must still be attempted (and succeed) despite the other relay's failure
+	err = m.PinAll(context.Background(), "chainC", blockchain.BroadcastBatch{}, AnchorProof{})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"chainC"}, chainA.registered)
+}
+
+func TestResolveUnknownChain(t *testing.T) {
+	chainA := &fakeRelay{chain: "chainA"}
+	m, err := NewInteropManager(context.Background(), map[string]RelayPlugin{"chainA": chainA})
+	assert.NoError(t, err)
+
+	_, err = m.Resolve(context.Background(), "chainZ", blockchain.Bytes32{})
+	assert.Error(t, err)
+}
+
+func TestResolveDelegatesToPlugin(t *testing.T) {
+	chainA := &fakeRelay{chain: "chainA"}
+	m, err := NewInteropManager(context.Background(), map[string]RelayPlugin{"chainA": chainA})
+	assert.NoError(t, err)
+
+	batchID := blockchain.Bytes32{0x01}
+	batch, err := m.Resolve(context.Background(), "chainA", batchID)
+	assert.NoError(t, err)
+	assert.Equal(t, batchID, batch.BatchID)
+}
@@ -0,0 +1,54 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package interop lets a firefly node pin the same batch of off-chain data to more than one blockchain
+// at once, so that remote firefly nodes anchored to a different chain than us can still validate it.
+package interop
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly/internal/blockchain"
+)
+
+// RelayPlugin is implemented by a chain-specific relay, which knows how to register an anchor proof on
+// its own chain, and how to verify an anchor proof that was registered by a remote node on that same chain.
+type RelayPlugin interface {
+
+	// Chain returns the identifier of the chain this plugin anchors to
+	Chain() string
+
+	// RegisterAnchor pins proof that batch was sequenced on sourceChain, so that a remote node anchored to
+	// this plugin's chain can later call VerifyAnchor to check it without needing access to sourceChain itself
+	RegisterAnchor(ctx context.Context, sourceChain string, batch blockchain.BroadcastBatch, proof AnchorProof) error
+
+	// VerifyAnchor checks that a BatchID pinned on destChain (this plugin's chain) has a valid inclusion proof,
+	// and returns the BroadcastBatch it resolves to so the caller can fetch/validate the off-chain payload
+	VerifyAnchor(ctx context.Context, destChain string, batchID blockchain.Bytes32) (*blockchain.BroadcastBatch, error)
+}
+
+// AnchorProof is a Merkle-Patricia inclusion proof (or chain-specific equivalent) that a batch was genuinely
+// sequenced on the source chain at the claimed position, opaque outside of the plugin that produced/consumes it
+type AnchorProof struct {
+	// Root is the Merkle (or Merkle-Patricia) root the proof resolves to
+	Root blockchain.Bytes32
+
+	// Path is the ordered set of sibling hashes needed to walk from the leaf up to Root
+	Path []blockchain.Bytes32
+
+	// LeafIndex is the position of the leaf (the pinned batch) within the tree Root was computed over
+	LeafIndex uint64
+}
@@ -0,0 +1,68 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+// Bytes32 is this package's own copy of blockchain.Bytes32's shape - a 32 byte fixed length binary value,
+// such as a batch ID or Merkle root - duplicated here (rather than imported) so this protocol-agnostic
+// public type never needs to reach into an internal plugin package just to describe its own wire format
+type Bytes32 [32]byte
+
+// HexUUID is this package's own copy of blockchain.HexUUID's shape - a 16 byte fixed length binary value
+// used as a payload ref - duplicated here for the same reason as Bytes32 above
+type HexUUID [16]byte
+
+// AnchorBatch is this package's own copy of blockchain.BroadcastBatch's shape, carrying only the fields the
+// network/anchor API needs to identify the batch being anchored
+type AnchorBatch struct {
+	// Timestamp is the time of submission, from the perspective of the original submitter
+	Timestamp uint64 `json:"timestamp"`
+
+	// BatchPayloadRef is passed to the storage interface to retrieve the off-chain payload
+	BatchPayloadRef HexUUID `json:"batchPayloadRef"`
+
+	// BatchID is the id of the batch, as written in plain text to SourceChain
+	BatchID Bytes32 `json:"batchID"`
+}
+
+// AnchorProof is this package's own copy of interop.AnchorProof's shape - a Merkle-Patricia inclusion proof
+// (or chain-specific equivalent) that Batch was genuinely sequenced on SourceChain at the claimed position
+type AnchorProof struct {
+	// Root is the Merkle (or Merkle-Patricia) root the proof resolves to
+	Root Bytes32 `json:"root"`
+
+	// Path is the ordered set of sibling hashes needed to walk from the leaf up to Root
+	Path []Bytes32 `json:"path"`
+
+	// LeafIndex is the position of the leaf (the pinned batch) within the tree Root was computed over
+	LeafIndex uint64 `json:"leafIndex"`
+}
+
+// NetworkAnchor is the request body for POST /network/anchor: an explicit instruction to pin a previously
+// sequenced batch onto every other configured relay chain, analogous to NetworkAction's role as a manual
+// trigger for an otherwise-automatic network operation. Batch and Proof are pkg/core-local types rather than
+// the internal/blockchain and internal/interop types they mirror, since pkg/core must stay usable without
+// pulling in any blockchain/interop plugin internals - the apiserver route converts between the two.
+type NetworkAnchor struct {
+	// SourceChain is the chain Batch was originally sequenced on
+	SourceChain string `json:"sourceChain"`
+
+	// Batch is the previously sequenced batch to anchor
+	Batch AnchorBatch `json:"batch"`
+
+	// Proof is the inclusion proof of Batch on SourceChain, as produced by that chain's interop.RelayPlugin
+	Proof AnchorProof `json:"proof"`
+}
@@ -0,0 +1,65 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sharedstorage defines the interface implemented by each shared storage plugin - a place outside
+// of firefly core itself (IPFS, S3, etc.) where the off-chain payload of a batch can be stored and retrieved
+// by every member of the network, addressed by the payloadRef written into the on-chain pin.
+package sharedstorage
+
+import (
+	"context"
+	"io"
+
+	"github.com/hyperledger/firefly/internal/config"
+)
+
+// Plugin is the interface implemented by each shared storage plugin
+type Plugin interface {
+
+	// InitPrefix initializes the set of configuration options that are valid, with defaults. Called on all
+	// plugins, before Init
+	InitPrefix(prefix config.Prefix)
+
+	// Init initializes the plugin, reading its configuration from prefix (as declared by InitPrefix)
+	Init(ctx context.Context, prefix config.Prefix, callbacks Callbacks) error
+
+	// Capabilities returns the supported featureset of the interface implemented by the plugin, with the
+	// specified config
+	Capabilities() *Capabilities
+
+	// Name returns the name of this plugin
+	Name() string
+
+	// UploadData uploads data from the provided reader, and returns a payloadRef that can be used to
+	// retrieve it again with DownloadData
+	UploadData(ctx context.Context, data io.Reader) (payloadRef string, err error)
+
+	// DownloadData retrieves data previously stored via UploadData, identified by payloadRef
+	DownloadData(ctx context.Context, payloadRef string) (io.ReadCloser, error)
+}
+
+// Callbacks is the interface provided to the shared storage plugin, to allow it to pass events back to firefly
+type Callbacks interface {
+}
+
+// Capabilities the supported featureset of the shared storage interface implemented by the plugin
+type Capabilities struct {
+	// SupportsRepair indicates the plugin can usefully be asked to re-check previously uploaded data and
+	// repair/re-upload any part of it that has gone missing or corrupt, rather than that being a permanent
+	// loss. Plugins that don't hold redundant/recoverable data (a plain single-backend plugin, for example)
+	// should leave this false.
+	SupportsRepair bool
+}
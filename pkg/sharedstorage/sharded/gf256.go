@@ -0,0 +1,196 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharded
+
+// This file implements the GF(2^8) field arithmetic and the encoding-matrix construction needed for a
+// Reed-Solomon erasure code - built from a Cauchy matrix, not a Vandermonde one (see cauchy) - using the same primitive polynomial (x^8+x^4+x^3+x^2+1, 0x11d) as
+// most erasure-coding libraries so shard layouts are easy to reason about against reference implementations.
+
+const gfPoly = 0x11d
+
+var gfExp [512]byte
+var gfLog [256]byte
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= gfPoly
+		}
+	}
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfAdd(a, b byte) byte { return a ^ b }
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// matrix is a row-major byte matrix over GF(2^8)
+type matrix [][]byte
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for i := range m {
+		m[i] = make([]byte, cols)
+	}
+	return m
+}
+
+// cauchy builds a (k+m) x k encoding matrix that is provably MDS (every k x k submatrix is invertible, so
+// any k of the k+m resulting shards are enough to recover the k data shards): start from a Cauchy matrix
+// raw[i][j] = 1/(x_i ^ y_j) over disjoint sets of distinct x_i, y_j - a Cauchy matrix is MDS because every
+// minor of it is a nonzero ratio of Cauchy determinants - then row-reduce it by right-multiplying by the
+// inverse of its own top k x k block, so the top k rows become the identity (data shards pass through
+// unmodified). Multiplying an MDS matrix by an invertible k x k matrix keeps it MDS, since invertibility of
+// any k selected rows of the product depends only on those rows of the Cauchy factor.
+//
+// A naive identity-stacked-on-Vandermonde matrix does not have this guarantee for every (k, m): some k-row
+// submatrices can be singular, which silently breaks the "any k of n shards reconstructs" property.
+func cauchy(k, m int) matrix {
+	rows := k + m
+	x := make([]byte, rows)
+	y := make([]byte, k)
+	for i := 0; i < rows; i++ {
+		x[i] = byte(i)
+	}
+	for j := 0; j < k; j++ {
+		y[j] = byte(rows + j)
+	}
+
+	raw := newMatrix(rows, k)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < k; j++ {
+			raw[i][j] = gfDiv(1, gfAdd(x[i], y[j]))
+		}
+	}
+
+	top := raw.subMatrix(identityRows(k))
+	topInv, err := top.invert()
+	if err != nil {
+		// unreachable: a Cauchy matrix's leading k x k block is itself a square Cauchy matrix, always invertible
+		panic(err)
+	}
+	return raw.multiplyMatrix(topInv)
+}
+
+// identityRows returns []int{0, 1, ..., n-1}
+func identityRows(n int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+// subMatrix extracts the rows of mat at the given indices
+func (mat matrix) subMatrix(rowIdx []int) matrix {
+	out := make(matrix, len(rowIdx))
+	for i, r := range rowIdx {
+		out[i] = mat[r]
+	}
+	return out
+}
+
+// invert returns the inverse of a square matrix over GF(2^8) via Gauss-Jordan elimination
+func (mat matrix) invert() (matrix, error) {
+	n := len(mat)
+	aug := newMatrix(n, 2*n)
+	for i := 0; i < n; i++ {
+		copy(aug[i][:n], mat[i])
+		aug[i][n+i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for r := col; r < n; r++ {
+			if aug[r][col] != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot == -1 {
+			return nil, errSingularMatrix
+		}
+		aug[col], aug[pivot] = aug[pivot], aug[col]
+
+		inv := gfDiv(1, aug[col][col])
+		for c := 0; c < 2*n; c++ {
+			aug[col][c] = gfMul(aug[col][c], inv)
+		}
+		for r := 0; r < n; r++ {
+			if r == col || aug[r][col] == 0 {
+				continue
+			}
+			factor := aug[r][col]
+			for c := 0; c < 2*n; c++ {
+				aug[r][c] = gfAdd(aug[r][c], gfMul(factor, aug[col][c]))
+			}
+		}
+	}
+
+	out := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		copy(out[i], aug[i][n:])
+	}
+	return out, nil
+}
+
+// multiply computes mat x vec, where vec is a column of single bytes (one byte from each of mat's columns)
+func (mat matrix) multiply(vec []byte) []byte {
+	out := make([]byte, len(mat))
+	for r, row := range mat {
+		var acc byte
+		for c, v := range row {
+			acc = gfAdd(acc, gfMul(v, vec[c]))
+		}
+		out[r] = acc
+	}
+	return out
+}
+
+// multiplyMatrix computes mat x other, where mat is r x n and other is n x c
+func (mat matrix) multiplyMatrix(other matrix) matrix {
+	cols := len(other[0])
+	out := newMatrix(len(mat), cols)
+	for i, row := range mat {
+		for j := 0; j < cols; j++ {
+			var acc byte
+			for c, v := range row {
+				acc = gfAdd(acc, gfMul(v, other[c][j]))
+			}
+			out[i][j] = acc
+		}
+	}
+	return out
+}
@@ -0,0 +1,567 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sharded implements a sharedstorage.Plugin that fans a payload out, erasure coded, across N
+// underlying sharedstorage.Plugin backends - so the payload survives the loss of any m of those backends,
+// without needing to store m full redundant copies.
+package sharded
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly/internal/config"
+	"github.com/hyperledger/firefly/internal/i18n"
+	"github.com/hyperledger/firefly/internal/log"
+	"github.com/hyperledger/firefly/pkg/sharedstorage"
+)
+
+// defaultShardSize is the size each data shard is split/padded to before encoding, absent config override
+const defaultShardSize = 1024 * 1024
+
+var errSingularMatrix = errors.New("matrix is not invertible")
+
+// manifest is the small document that ties a set of erasure-coded shards back together. It is content -
+// every backend's own ref for it is recorded in the CID (see cidPayload), so it is never a single point of
+// failure the way a single shared ref would be.
+type manifest struct {
+	TotalSize    int64 `json:"totalSize"`
+	ShardSize    int   `json:"shardSize"`
+	DataShards   int   `json:"dataShards"`
+	ParityShards int   `json:"parityShards"`
+	// Stripes is how many stripes of up to DataShards*ShardSize bytes the payload was split into (1 unless
+	// it exceeded DataShards*ShardSize bytes). Each of ShardRefs' k+m backends holds one blob containing its shard from
+	// every stripe concatenated in order, so there is still exactly one ref per backend regardless of
+	// payload size.
+	Stripes   int      `json:"stripes"`
+	ShardRefs []string `json:"shardRefs"`
+	// ShardDigests is the sha256 hex digest of each backend's full blob (all its stripes' shards
+	// concatenated, including any zero padding), checked before a downloaded blob is trusted for
+	// reconstruction
+	ShardDigests []string `json:"shardDigests"`
+}
+
+// cidPayload is the plugin-generated CID returned from UploadData. It is self-describing: rather than
+// relying on every backend producing the same ref for identical bytes (only true of genuinely
+// content-addressed backends), it carries each backend's own ref for the manifest blob directly, alongside
+// a digest the manifest content is checked against once retrieved.
+type cidPayload struct {
+	Digest       string   `json:"digest"`
+	ManifestRefs []string `json:"manifestRefs"`
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Plugin wraps a fixed set of backend sharedstorage.Plugin instances (one per shard) and implements
+// Reed-Solomon erasure coding across them
+type Plugin struct {
+	backends  []sharedstorage.Plugin
+	shardSize int
+	k         int // data shards
+	m         int // parity shards
+	encMatrix matrix
+
+	mux      sync.Mutex
+	repaired map[string]map[int]string // cid -> shard index -> replacement ref, populated by Repair
+}
+
+// New constructs a sharded Plugin. len(backends) must equal k+m - one backend is dedicated to each shard.
+func New(backends []sharedstorage.Plugin, k, m, shardSize int) (*Plugin, error) {
+	if len(backends) != k+m {
+		return nil, i18n.NewError(context.Background(), i18n.MsgShardedBackendCountMismatch, len(backends), k+m)
+	}
+	if shardSize <= 0 {
+		shardSize = defaultShardSize
+	}
+	return &Plugin{
+		backends:  backends,
+		shardSize: shardSize,
+		k:         k,
+		m:         m,
+		encMatrix: cauchy(k, m),
+		repaired:  make(map[string]map[int]string),
+	}, nil
+}
+
+func (p *Plugin) Name() string { return "sharded" }
+
+func (p *Plugin) InitPrefix(prefix config.Prefix) {
+	for _, b := range p.backends {
+		b.InitPrefix(prefix)
+	}
+}
+
+func (p *Plugin) Init(ctx context.Context, prefix config.Prefix, callbacks sharedstorage.Callbacks) error {
+	for _, b := range p.backends {
+		if err := b.Init(ctx, prefix, callbacks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) Capabilities() *sharedstorage.Capabilities {
+	return &sharedstorage.Capabilities{
+		SupportsRepair: true,
+	}
+}
+
+// UploadData splits data into one or more stripes of up to p.k*p.shardSize bytes (zero-padding the last shard of the last
+// stripe as needed), computes p.m parity shards per stripe via the Cauchy encoding matrix, concatenates each
+// backend's shard across every stripe into a single blob, uploads all k+m blobs concurrently (one per
+// backend), and returns the CID of a manifest describing how to find, verify and reassemble them.
+func (p *Plugin) UploadData(ctx context.Context, data io.Reader) (string, error) {
+	raw, err := ioutil.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+
+	allShards := p.buildShardBlobs(raw)
+
+	refs, err := p.uploadShards(ctx, allShards)
+	if err != nil {
+		return "", err
+	}
+
+	digests := make([]string, len(allShards))
+	for i, s := range allShards {
+		digests[i] = sha256Hex(s)
+	}
+
+	man := &manifest{
+		TotalSize:    int64(len(raw)),
+		ShardSize:    p.shardSize,
+		DataShards:   p.k,
+		ParityShards: p.m,
+		Stripes:      p.numStripes(len(raw)),
+		ShardRefs:    refs,
+		ShardDigests: digests,
+	}
+	manBytes, err := json.Marshal(man)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256Hex(manBytes)
+
+	// Upload the manifest to every backend, but - unlike a naive "last ref wins" - keep each backend's own
+	// ref, so the CID we return doesn't assume backends are content-addressed with a shared hash scheme
+	manifestRefs := make([]string, len(p.backends))
+	var wg sync.WaitGroup
+	errs := make([]error, len(p.backends))
+	for i, b := range p.backends {
+		wg.Add(1)
+		go func(i int, b sharedstorage.Plugin) {
+			defer wg.Done()
+			manifestRefs[i], errs[i] = b.UploadData(ctx, bytes.NewReader(manBytes))
+		}(i, b)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return "", err
+		}
+	}
+
+	cidBytes, err := json.Marshal(&cidPayload{Digest: digest, ManifestRefs: manifestRefs})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(cidBytes), nil
+}
+
+func (p *Plugin) uploadShards(ctx context.Context, shards [][]byte) ([]string, error) {
+	refs := make([]string, len(shards))
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard []byte) {
+			defer wg.Done()
+			refs[i], errs[i] = p.backends[i].UploadData(ctx, bytes.NewReader(shard))
+		}(i, shard)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+// DownloadData resolves cid to its manifest, fetches as many digest-verified shards as it can (in
+// parallel), and reconstructs the original payload from any k of the k+m shards.
+func (p *Plugin) DownloadData(ctx context.Context, cid string) (io.ReadCloser, error) {
+	man, err := p.fetchManifest(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	good, have := p.fetchGoodShards(ctx, cid, man)
+	if have < man.DataShards {
+		return nil, i18n.NewError(ctx, i18n.MsgShardedInsufficientShards, have, man.DataShards)
+	}
+
+	payload, err := p.reconstruct(ctx, man, good)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(payload)), nil
+}
+
+// fetchGoodShards downloads every shard it can reach (honouring any repaired overrides) and discards any
+// whose bytes don't match the manifest's recorded digest for that shard, so corrupt-but-present data is
+// never silently treated as good.
+func (p *Plugin) fetchGoodShards(ctx context.Context, cid string, man *manifest) (shards [][]byte, have int) {
+	shards = make([][]byte, len(man.ShardRefs))
+
+	p.mux.Lock()
+	overrides := p.repaired[cid]
+	p.mux.Unlock()
+
+	var wg sync.WaitGroup
+	for i, ref := range man.ShardRefs {
+		useRef := ref
+		if overrides != nil {
+			if r, ok := overrides[i]; ok {
+				useRef = r
+			}
+		}
+		wg.Add(1)
+		go func(i int, ref string) {
+			defer wg.Done()
+			rc, err := p.backends[i].DownloadData(ctx, ref)
+			if err != nil {
+				return
+			}
+			defer rc.Close()
+			b, err := ioutil.ReadAll(rc)
+			if err != nil {
+				return
+			}
+			if sha256Hex(b) != man.ShardDigests[i] {
+				log.L(ctx).Warnf("Discarding shard %d (ref=%s): digest mismatch", i, ref)
+				return
+			}
+			shards[i] = b
+		}(i, useRef)
+	}
+	wg.Wait()
+
+	for _, s := range shards {
+		if s != nil {
+			have++
+		}
+	}
+	return shards, have
+}
+
+func (p *Plugin) fetchManifest(ctx context.Context, cid string) (*manifest, error) {
+	cidBytes, err := base64.RawURLEncoding.DecodeString(cid)
+	if err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgShardedManifestUnavailable, cid, err)
+	}
+	var cp cidPayload
+	if err := json.Unmarshal(cidBytes, &cp); err != nil {
+		return nil, i18n.NewError(ctx, i18n.MsgShardedManifestUnavailable, cid, err)
+	}
+
+	var lastErr error
+	for i, ref := range cp.ManifestRefs {
+		if ref == "" {
+			continue
+		}
+		rc, err := p.backends[i].DownloadData(ctx, ref)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		manBytes, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if sha256Hex(manBytes) != cp.Digest {
+			lastErr = i18n.NewError(ctx, i18n.MsgShardedManifestCorrupt, i)
+			continue
+		}
+		man := &manifest{}
+		if err := json.Unmarshal(manBytes, man); err != nil {
+			lastErr = err
+			continue
+		}
+		return man, nil
+	}
+	return nil, i18n.NewError(ctx, i18n.MsgShardedManifestUnavailable, cid, lastErr)
+}
+
+// Repair re-checks every shard of the manifest addressed by cid, and re-uploads a freshly reconstructed
+// copy of any shard that is missing or fails its digest check, so a transient backend loss does not
+// silently erode the payload's ability to tolerate further losses. It returns the number of shards it
+// repaired. Repair can only recover shards when at least DataShards of the original k+m are still good -
+// beyond that point the payload is unrecoverable, and Repair returns an error rather than guessing.
+func (p *Plugin) Repair(ctx context.Context, cid string) (int, error) {
+	man, err := p.fetchManifest(ctx, cid)
+	if err != nil {
+		return 0, err
+	}
+
+	good, have := p.fetchGoodShards(ctx, cid, man)
+	if have < man.DataShards {
+		return 0, i18n.NewError(ctx, i18n.MsgShardedInsufficientShards, have, man.DataShards)
+	}
+
+	var badIdx []int
+	for i, s := range good {
+		if s == nil {
+			badIdx = append(badIdx, i)
+		}
+	}
+	if len(badIdx) == 0 {
+		return 0, nil
+	}
+
+	// Rebuild every backend's blob (data + parity, across every stripe) from the data we do have, so we can
+	// re-upload replacements for exactly the slots that are missing, without needing the original payload again
+	payload, err := p.reconstruct(ctx, man, good)
+	if err != nil {
+		return 0, err
+	}
+	rebuiltAll := p.buildShardBlobs(payload)
+
+	p.mux.Lock()
+	if p.repaired[cid] == nil {
+		p.repaired[cid] = make(map[int]string)
+	}
+	p.mux.Unlock()
+
+	repaired := 0
+	for _, i := range badIdx {
+		ref, err := p.backends[i].UploadData(ctx, bytes.NewReader(rebuiltAll[i]))
+		if err != nil {
+			log.L(ctx).Errorf("Failed to repair shard %d of %s: %s", i, cid, err)
+			continue
+		}
+		p.mux.Lock()
+		p.repaired[cid][i] = ref
+		p.mux.Unlock()
+		repaired++
+	}
+	return repaired, nil
+}
+
+// StartRepairLoop runs Repair against every CID returned by manifestCIDs(), every interval, until ctx is
+// cancelled. The caller owns the set of CIDs worth watching (e.g. the batch manager's outstanding
+// payloadRefs) - this loop only knows how to check and fix the ones it's told about.
+func (p *Plugin) StartRepairLoop(ctx context.Context, interval time.Duration, manifestCIDs func() []string) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, cid := range manifestCIDs() {
+					repaired, err := p.Repair(ctx, cid)
+					if err != nil {
+						log.L(ctx).Warnf("Repair check failed for %s: %s", cid, err)
+						continue
+					}
+					if repaired > 0 {
+						log.L(ctx).Infof("Repaired %d shard(s) of %s", repaired, cid)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// numStripes returns how many stripes of p.k*p.shardSize bytes a payload of rawLen bytes splits into - always at
+// least 1, even for an empty payload, so a manifest is never built with zero stripes
+func (p *Plugin) numStripes(rawLen int) int {
+	stripeSize := p.k * p.shardSize
+	n := (rawLen + stripeSize - 1) / stripeSize
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+// buildShardBlobs splits raw into p.numStripes(len(raw)) stripes of up to p.k*p.shardSize bytes each,
+// encodes every stripe's p.k data shards plus p.m parity shards, and returns one blob per backend holding
+// that backend's shard from every stripe concatenated in order - so a payload larger than a single stripe
+// is still represented by exactly one ref per backend
+func (p *Plugin) buildShardBlobs(raw []byte) [][]byte {
+	stripeSize := p.k * p.shardSize
+	numStripes := p.numStripes(len(raw))
+
+	blobs := make([][]byte, p.k+p.m)
+	for i := range blobs {
+		blobs[i] = make([]byte, 0, numStripes*p.shardSize)
+	}
+	for s := 0; s < numStripes; s++ {
+		start := s * stripeSize
+		end := start + stripeSize
+		if end > len(raw) {
+			end = len(raw)
+		}
+		dataShards := p.splitShards(raw[start:end])
+		parity := p.encodeParity(dataShards)
+		stripeShards := append(dataShards, parity...)
+		for i, shard := range stripeShards {
+			blobs[i] = append(blobs[i], shard...)
+		}
+	}
+	return blobs
+}
+
+// splitShards divides a single stripe's raw bytes (at most p.k*p.shardSize) into k fixed-size shards,
+// zero-padding the final one as needed
+func (p *Plugin) splitShards(raw []byte) [][]byte {
+	shards := make([][]byte, p.k)
+	for i := 0; i < p.k; i++ {
+		shard := make([]byte, p.shardSize)
+		start := i * p.shardSize
+		if start < len(raw) {
+			end := start + p.shardSize
+			if end > len(raw) {
+				end = len(raw)
+			}
+			copy(shard, raw[start:end])
+		}
+		shards[i] = shard
+	}
+	return shards
+}
+
+// encodeParity computes the m parity shards as the bottom m rows of p.encMatrix applied to the k data shards
+func (p *Plugin) encodeParity(dataShards [][]byte) [][]byte {
+	parity := make([][]byte, p.m)
+	for r := 0; r < p.m; r++ {
+		parity[r] = make([]byte, p.shardSize)
+		row := p.encMatrix[p.k+r]
+		for byteIdx := 0; byteIdx < p.shardSize; byteIdx++ {
+			var acc byte
+			for c := 0; c < p.k; c++ {
+				acc = gfAdd(acc, gfMul(row[c], dataShards[c][byteIdx]))
+			}
+			parity[r][byteIdx] = acc
+		}
+	}
+	return parity
+}
+
+// reconstruct recovers the original payload from any k of the available k+m per-backend blobs (blobs[i] ==
+// nil means that backend is unavailable/discarded), one stripe at a time - a payload larger than a single
+// stripe (p.k*p.shardSize bytes) is spread across man.Stripes of them, each held at the same byte offset in
+// every blob
+func (p *Plugin) reconstruct(ctx context.Context, man *manifest, blobs [][]byte) ([]byte, error) {
+	var out []byte
+	for s := 0; s < man.Stripes; s++ {
+		start := s * man.ShardSize
+		end := start + man.ShardSize
+
+		stripeShards := make([][]byte, len(blobs))
+		for i, blob := range blobs {
+			if blob == nil {
+				continue
+			}
+			if end > len(blob) {
+				return nil, i18n.NewError(ctx, i18n.MsgShardedManifestCorrupt, i)
+			}
+			stripeShards[i] = blob[start:end]
+		}
+
+		stripeOut, err := p.reconstructStripe(man, stripeShards)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, stripeOut...)
+	}
+
+	if int64(len(out)) > man.TotalSize {
+		out = out[:man.TotalSize]
+	}
+	return out, nil
+}
+
+// reconstructStripe recovers one stripe's k*ShardSize bytes (including any zero padding) from any k of that
+// stripe's k+m shards (shards[i] == nil means unavailable/discarded), inverting the corresponding k rows of
+// the encoding matrix and applying that inverse to each byte column
+func (p *Plugin) reconstructStripe(man *manifest, shards [][]byte) ([]byte, error) {
+	rowIdx := make([]int, 0, man.DataShards)
+	for i, s := range shards {
+		if s != nil {
+			rowIdx = append(rowIdx, i)
+			if len(rowIdx) == man.DataShards {
+				break
+			}
+		}
+	}
+
+	// Fast path: the first k shards (the unmodified data shards) all survived - no decode matrix needed
+	allData := true
+	for i := 0; i < man.DataShards; i++ {
+		if shards[i] == nil {
+			allData = false
+			break
+		}
+	}
+	var out []byte
+	if allData {
+		for i := 0; i < man.DataShards; i++ {
+			out = append(out, shards[i]...)
+		}
+	} else {
+		sub := p.encMatrix.subMatrix(rowIdx)
+		inv, err := sub.invert()
+		if err != nil {
+			return nil, err
+		}
+		recovered := make([][]byte, man.DataShards)
+		for i := range recovered {
+			recovered[i] = make([]byte, man.ShardSize)
+		}
+		for byteIdx := 0; byteIdx < man.ShardSize; byteIdx++ {
+			vec := make([]byte, len(rowIdx))
+			for j, r := range rowIdx {
+				vec[j] = shards[r][byteIdx]
+			}
+			col := inv.multiply(vec)
+			for i := 0; i < man.DataShards; i++ {
+				recovered[i][byteIdx] = col[i]
+			}
+		}
+		for _, shard := range recovered {
+			out = append(out, shard...)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,288 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharded
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly/internal/config"
+	"github.com/hyperledger/firefly/pkg/sharedstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+// memBackend is a minimal content-addressed in-memory sharedstorage.Plugin, for exercising the sharded
+// plugin's fan-out/reconstruction logic without any real network dependency
+type memBackend struct {
+	mux  sync.Mutex
+	data map[string][]byte
+	down bool
+}
+
+func newMemBackend() *memBackend { return &memBackend{data: make(map[string][]byte)} }
+
+func (b *memBackend) Name() string                    { return "mem" }
+func (b *memBackend) InitPrefix(prefix config.Prefix) {}
+func (b *memBackend) Init(ctx context.Context, prefix config.Prefix, cb sharedstorage.Callbacks) error {
+	return nil
+}
+func (b *memBackend) Capabilities() *sharedstorage.Capabilities { return &sharedstorage.Capabilities{} }
+
+func (b *memBackend) UploadData(ctx context.Context, data io.Reader) (string, error) {
+	content, err := ioutil.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	ref := hex.EncodeToString(sum[:])
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.data[ref] = content
+	return ref, nil
+}
+
+func (b *memBackend) DownloadData(ctx context.Context, ref string) (io.ReadCloser, error) {
+	if b.down {
+		return nil, errors.New("backend unavailable")
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	content, ok := b.data[ref]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// corrupt flips a byte in the stored content for ref, simulating silent bit-rot at the backend
+func (b *memBackend) corrupt(ref string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if content, ok := b.data[ref]; ok && len(content) > 0 {
+		content[0] ^= 0xff
+	}
+}
+
+func newTestPlugin(t *testing.T, k, m int) (*Plugin, []*memBackend) {
+	backends := make([]*memBackend, k+m)
+	pluginBackends := make([]sharedstorage.Plugin, k+m)
+	for i := range backends {
+		backends[i] = newMemBackend()
+		pluginBackends[i] = backends[i]
+	}
+	p, err := New(pluginBackends, k, m, 16)
+	assert.NoError(t, err)
+	return p, backends
+}
+
+func TestRoundTripNoLoss(t *testing.T) {
+	p, _ := newTestPlugin(t, 3, 2)
+	ctx := context.Background()
+
+	payload := []byte("the quick brown fox jumps over the lazy dog, 1234567890")
+	cid, err := p.UploadData(ctx, bytes.NewReader(payload))
+	assert.NoError(t, err)
+
+	rc, err := p.DownloadData(ctx, cid)
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, out)
+}
+
+func TestReconstructAfterBackendLoss(t *testing.T) {
+	p, backends := newTestPlugin(t, 3, 2)
+	ctx := context.Background()
+
+	payload := []byte("payload that must survive losing two of five backends entirely")
+	cid, err := p.UploadData(ctx, bytes.NewReader(payload))
+	assert.NoError(t, err)
+
+	// lose two of the five backends (the max this (k=3, m=2) configuration can tolerate)
+	backends[0].down = true
+	backends[3].down = true
+
+	rc, err := p.DownloadData(ctx, cid)
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, out)
+}
+
+func TestTooManyLossesFails(t *testing.T) {
+	p, backends := newTestPlugin(t, 3, 2)
+	ctx := context.Background()
+
+	cid, err := p.UploadData(ctx, bytes.NewReader([]byte("short")))
+	assert.NoError(t, err)
+
+	backends[0].down = true
+	backends[1].down = true
+	backends[2].down = true
+
+	_, err = p.DownloadData(ctx, cid)
+	assert.Error(t, err)
+}
+
+func TestCorruptShardIsDiscardedNotTrusted(t *testing.T) {
+	p, backends := newTestPlugin(t, 3, 2)
+	ctx := context.Background()
+
+	payload := []byte("a payload where one backend silently returns corrupted bytes")
+	cid, err := p.UploadData(ctx, bytes.NewReader(payload))
+	assert.NoError(t, err)
+
+	man, err := p.fetchManifest(ctx, cid)
+	assert.NoError(t, err)
+	backends[0].corrupt(man.ShardRefs[0])
+
+	// corruption of one shard is still within the (k=3, m=2) tolerance, so reconstruction should still
+	// succeed - but only because the corrupt shard is detected and excluded, not decoded as-is
+	rc, err := p.DownloadData(ctx, cid)
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, out)
+}
+
+func TestRepairReuploadsMissingShard(t *testing.T) {
+	p, backends := newTestPlugin(t, 3, 2)
+	ctx := context.Background()
+	assert.True(t, p.Capabilities().SupportsRepair)
+
+	payload := []byte("payload that should be auto-repaired after a backend drops a shard")
+	cid, err := p.UploadData(ctx, bytes.NewReader(payload))
+	assert.NoError(t, err)
+
+	backends[4].down = true
+	repaired, err := p.Repair(ctx, cid)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+
+	// bring backend 4 back and lose two previously-healthy backends: the three remaining shards (backend 4's
+	// served via its repaired ref) are exactly k, so reconstruction must still succeed
+	backends[4].down = false
+	backends[0].down = true
+	backends[1].down = true
+
+	rc, err := p.DownloadData(ctx, cid)
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, out)
+}
+
+func TestRoundTripPayloadLargerThanSingleStripe(t *testing.T) {
+	p, _ := newTestPlugin(t, 3, 2) // shardSize=16, so one stripe holds k*shardSize=48 bytes
+	ctx := context.Background()
+
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 10) // 160 bytes, spans 4 stripes
+	cid, err := p.UploadData(ctx, bytes.NewReader(payload))
+	assert.NoError(t, err)
+
+	rc, err := p.DownloadData(ctx, cid)
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, out)
+}
+
+// TestEveryKSubsetReconstructs guards against a non-MDS encoding matrix: with a naive identity-stacked-on-
+// Vandermonde matrix, some subsets of k surviving shards fail to invert even though len(subset) == k. Every
+// one of the C(k+m, k) subsets must reconstruct the payload, not just one arbitrarily-chosen loss pattern.
+func TestEveryKSubsetReconstructs(t *testing.T) {
+	const k, m = 4, 3
+	payload := []byte("every combination of k surviving shards must be able to reconstruct this payload")
+
+	var subsets [][]int
+	var choose func(start int, chosen []int)
+	choose = func(start int, chosen []int) {
+		if len(chosen) == k {
+			subsets = append(subsets, append([]int(nil), chosen...))
+			return
+		}
+		for i := start; i < k+m; i++ {
+			choose(i+1, append(chosen, i))
+		}
+	}
+	choose(0, nil)
+
+	for _, subset := range subsets {
+		p, backends := newTestPlugin(t, k, m)
+		ctx := context.Background()
+
+		cid, err := p.UploadData(ctx, bytes.NewReader(payload))
+		assert.NoError(t, err)
+
+		alive := make(map[int]bool, len(subset))
+		for _, i := range subset {
+			alive[i] = true
+		}
+		for i, b := range backends {
+			if !alive[i] {
+				b.down = true
+			}
+		}
+
+		rc, err := p.DownloadData(ctx, cid)
+		if !assert.NoError(t, err, "subset %v failed to reconstruct", subset) {
+			continue
+		}
+		out, err := ioutil.ReadAll(rc)
+		assert.NoError(t, err)
+		assert.Equal(t, payload, out, "subset %v reconstructed wrong payload", subset)
+	}
+}
+
+func TestStartRepairLoopRuns(t *testing.T) {
+	p, backends := newTestPlugin(t, 3, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cid, err := p.UploadData(ctx, bytes.NewReader([]byte("watched by the repair loop")))
+	assert.NoError(t, err)
+	backends[4].down = true
+
+	done := make(chan struct{})
+	p.StartRepairLoop(ctx, 10*time.Millisecond, func() []string { return []string{cid} })
+	go func() {
+		for i := 0; i < 50; i++ {
+			time.Sleep(10 * time.Millisecond)
+			p.mux.Lock()
+			_, ok := p.repaired[cid][4]
+			p.mux.Unlock()
+			if ok {
+				close(done)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("repair loop never repaired the missing shard")
+	}
+}